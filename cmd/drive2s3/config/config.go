@@ -20,15 +20,20 @@ import (
 	"errors"
 	"fmt"
 	"os"
+	"strings"
 	"time"
 
 	"github.com/minio/minio-go/v7"
 	"github.com/minio/minio-go/v7/pkg/credentials"
+	"github.com/pluto-org-co/fsio/filesystem"
+	"github.com/pluto-org-co/fsio/filesystem/cryptfs"
 	"github.com/pluto-org-co/fsio/filesystem/googledrive"
 	"github.com/pluto-org-co/fsio/filesystem/s3"
 	"github.com/pluto-org-co/fsio/googleutils"
+	"github.com/pluto-org-co/fsio/ioutils"
 	"golang.org/x/oauth2/google"
 	"golang.org/x/oauth2/jwt"
+	"gopkg.in/yaml.v3"
 )
 
 type (
@@ -46,22 +51,250 @@ type (
 		Bucket       string        `yaml:"bucket"`
 		CacheExpiry  time.Duration `yaml:"cache-expiry"`
 	}
+	// Limit replacing the default bandwidth during a daily time window
+	BandwidthWindow struct {
+		// Time of the day as HH:MM. Windows ending before they start span midnight
+		From string `yaml:"from"`
+		To   string `yaml:"to"`
+		// Weekday names like monday. Empty applies every day
+		Days []string `yaml:"days"`
+		// Bytes per second. Zero is unlimited
+		Limit int64 `yaml:"limit"`
+	}
+	// Bandwidth of the S3 transfers, shared by every worker
+	Bandwidth struct {
+		// Bytes per second. Zero is unlimited
+		Limit int64 `yaml:"limit"`
+		// The first window containing the current time replaces the limit
+		Schedule []BandwidthWindow `yaml:"schedule"`
+	}
+	// Files excluded from the sync. Mirrors never remove the bucket objects excluded by the filter
+	Filter struct {
+		// Glob patterns. Patterns without a slash match any location segment, patterns with one match leading segments
+		Include []string `yaml:"include"`
+		Exclude []string `yaml:"exclude"`
+		// Regular expressions matched against the slash joined location
+		IncludeRegex []string `yaml:"include-regex"`
+		ExcludeRegex []string `yaml:"exclude-regex"`
+		// Size bounds in bytes. Zero disables a bound
+		MinSize int64 `yaml:"min-size"`
+		MaxSize int64 `yaml:"max-size"`
+		// Mime types, mime type patterns like video/* or group names like office-like
+		ContentTypes        []string `yaml:"content-types"`
+		ExcludeContentTypes []string `yaml:"exclude-content-types"`
+		// Modification time age bounds. Zero disables a bound
+		MinAge time.Duration `yaml:"min-age"`
+		MaxAge time.Duration `yaml:"max-age"`
+	}
+	// Client-side encryption of the bucket objects. Disabled when no keys are configured
+	Encryption struct {
+		// File holding id:base64-secret keys, one per line. The first key encrypts new objects
+		KeyFile string `yaml:"key-file"`
+		// Environment variable holding the keys in the same format, used when key-file is empty
+		KeyEnv string `yaml:"key-env"`
+		// ID of the key encrypting the object names. Empty keeps the names in plain text
+		NamesKey string `yaml:"names-key"`
+	}
 	Config struct {
 		Workers  int           `yaml:"workers"`
 		Interval time.Duration `yaml:"interval"`
-		Drive    Drive         `yaml:"drive"`
-		S3       S3            `yaml:"s3"`
+		// Remove the bucket objects no longer found in Google Drive
+		Mirror bool `yaml:"mirror"`
+		// Maximum percentage of the bucket objects a single mirror may remove
+		MaxDeletePercent float64 `yaml:"max-delete-percent"`
+		// Stop with an error when more files fail in a single sync. Zero disables the check
+		MaxFailures int64 `yaml:"max-failures"`
+		// How unchanged files are detected: modtime, modtime-size, sha256 or etag. Defaults to modtime
+		Compare string `yaml:"compare"`
+		// Local journal remembering the synchronized files, so unchanged ones are skipped without
+		// querying the bucket and interrupted syncs resume. Empty compares every file on each sync
+		State string `yaml:"state"`
+		// Keep a dated snapshot of every run instead of a single copy, so previous days can be restored.
+		// Incompatible with mirror
+		Snapshot   bool       `yaml:"snapshot"`
+		Bandwidth  Bandwidth  `yaml:"bandwidth"`
+		Filter     Filter     `yaml:"filter"`
+		Encryption Encryption `yaml:"encryption"`
+		Drive      Drive      `yaml:"drive"`
+		S3         S3         `yaml:"s3"`
 	}
 )
 
-func (c *Config) S3Fs(ctx context.Context) (fs *s3.S3, err error) {
-	client, err := minio.New(
-		c.S3.Endpoint,
-		&minio.Options{
-			Creds:           credentials.NewStaticV4(c.S3.ClientId, c.S3.ClientSecret, ""),
-			TrailingHeaders: true,
-		},
+// Reads the configuration file
+func Load(filename string) (cfg *Config, err error) {
+	contents, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read file: %w", err)
+	}
+
+	cfg = &Config{}
+	err = yaml.Unmarshal(contents, cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal contents: %w", err)
+	}
+	return cfg, nil
+}
+
+// Parses HH:MM as the offset from midnight
+func parseTimeOfDay(value string) (offset time.Duration, err error) {
+	parsed, err := time.Parse("15:04", value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid time of day: %q: %w", value, err)
+	}
+	return time.Duration(parsed.Hour())*time.Hour + time.Duration(parsed.Minute())*time.Minute, nil
+}
+
+func parseWeekday(value string) (day time.Weekday, err error) {
+	for day := time.Sunday; day <= time.Saturday; day++ {
+		if strings.EqualFold(day.String(), value) {
+			return day, nil
+		}
+	}
+	return 0, fmt.Errorf("invalid weekday: %q", value)
+}
+
+func (w *BandwidthWindow) RateWindow() (window ioutils.RateWindow, err error) {
+	window.From, err = parseTimeOfDay(w.From)
+	if err != nil {
+		return window, fmt.Errorf("invalid from: %w", err)
+	}
+	window.To, err = parseTimeOfDay(w.To)
+	if err != nil {
+		return window, fmt.Errorf("invalid to: %w", err)
+	}
+	for _, value := range w.Days {
+		day, err := parseWeekday(value)
+		if err != nil {
+			return window, err
+		}
+		window.Days = append(window.Days, day)
+	}
+	window.BytesPerSecond = w.Limit
+	return window, nil
+}
+
+// Returns nil when the bandwidth is unlimited
+func (b *Bandwidth) RateLimiter() (limiter *ioutils.RateLimiter, err error) {
+	if b.Limit <= 0 && len(b.Schedule) == 0 {
+		return nil, nil
+	}
+
+	windows := make([]ioutils.RateWindow, 0, len(b.Schedule))
+	for index := range b.Schedule {
+		window, err := b.Schedule[index].RateWindow()
+		if err != nil {
+			return nil, fmt.Errorf("invalid schedule window %d: %w", index, err)
+		}
+		windows = append(windows, window)
+	}
+	return ioutils.NewRateLimiter(ioutils.ScheduledRate(b.Limit, windows)), nil
+}
+
+func (f *Filter) Filter() (filter *filesystem.Filter, err error) {
+	return filesystem.NewFilter(
+		filesystem.WithFilterOptionInclude(f.Include...),
+		filesystem.WithFilterOptionExclude(f.Exclude...),
+		filesystem.WithFilterOptionIncludeRegex(f.IncludeRegex...),
+		filesystem.WithFilterOptionExcludeRegex(f.ExcludeRegex...),
+		filesystem.WithFilterOptionSize(f.MinSize, f.MaxSize),
+		filesystem.WithFilterOptionContentTypes(f.ContentTypes...),
+		filesystem.WithFilterOptionExcludeContentTypes(f.ExcludeContentTypes...),
+		filesystem.WithFilterOptionAge(f.MinAge, f.MaxAge),
 	)
+}
+
+func (c *Config) SyncOptions() (options []filesystem.SyncOption, err error) {
+	if c.Snapshot && c.Mirror {
+		return nil, errors.New("snapshot and mirror can't be used together")
+	}
+
+	options = []filesystem.SyncOption{
+		filesystem.WithSyncOptionWorkers(c.Workers),
+		filesystem.WithSyncOptionMirror(c.Mirror),
+	}
+	if c.MaxDeletePercent > 0 {
+		options = append(options, filesystem.WithSyncOptionMaxDeletePercent(c.MaxDeletePercent))
+	}
+	if c.Compare != "" {
+		strategy, err := filesystem.ParseCompareStrategy(c.Compare)
+		if err != nil {
+			return nil, fmt.Errorf("invalid compare: %w", err)
+		}
+		// Encrypted objects only report their modification time
+		if c.Encryption.Enabled() && strategy != filesystem.CompareModTime && strategy != filesystem.CompareSha256 {
+			return nil, fmt.Errorf("invalid compare: %s can't be used with encryption", strategy)
+		}
+		options = append(options, filesystem.WithSyncOptionCompare(strategy))
+	}
+
+	filter, err := c.Filter.Filter()
+	if err != nil {
+		return nil, fmt.Errorf("invalid filter: %w", err)
+	}
+	options = append(options, filesystem.WithSyncOptionFilter(filter))
+	return options, nil
+}
+
+func (e *Encryption) Enabled() (ok bool) {
+	return e.KeyFile != "" || e.KeyEnv != ""
+}
+
+func (e *Encryption) Keyring() (keyring *cryptfs.Keyring, err error) {
+	if e.KeyFile != "" {
+		return cryptfs.LoadKeyFile(e.KeyFile)
+	}
+	return cryptfs.LoadKeyEnv(e.KeyEnv)
+}
+
+// Filesystem holding the backups, encrypting the objects when configured
+func (c *Config) BackupFs(ctx context.Context) (fs filesystem.Filesystem, err error) {
+	s3Fs, err := c.S3Fs(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if !c.Encryption.Enabled() {
+		return s3Fs, nil
+	}
+
+	keyring, err := c.Encryption.Keyring()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load encryption keys: %w", err)
+	}
+
+	var options []cryptfs.CryptOption
+	if c.Encryption.NamesKey != "" {
+		options = append(options, cryptfs.WithCryptOptionNames(c.Encryption.NamesKey))
+	}
+
+	fs, err = cryptfs.New(keyring, s3Fs, options...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to prepare encryption: %w", err)
+	}
+	return fs, nil
+}
+
+// The bandwidth limits every request sent to and response received from S3, so buffered
+// multipart uploads are sent at the limited rate too
+func (c *Config) S3Fs(ctx context.Context) (fs *s3.S3, err error) {
+	options := &minio.Options{
+		Creds:           credentials.NewStaticV4(c.S3.ClientId, c.S3.ClientSecret, ""),
+		TrailingHeaders: true,
+	}
+
+	limiter, err := c.Bandwidth.RateLimiter()
+	if err != nil {
+		return nil, fmt.Errorf("invalid bandwidth: %w", err)
+	}
+	if limiter != nil {
+		transport, err := minio.DefaultTransport(options.Secure)
+		if err != nil {
+			return nil, fmt.Errorf("failed to prepare transport: %w", err)
+		}
+		options.Transport = ioutils.NewRateLimitTransport(transport, limiter)
+	}
+
+	client, err := minio.New(c.S3.Endpoint, options)
 	if err != nil {
 		return nil, fmt.Errorf("failed to prepare client: %w", err)
 	}
@@ -98,7 +331,11 @@ func (c *Config) DriveFs(ctx context.Context) (fs *googledrive.GoogleDrive, err
 	})
 
 	var found bool
-	for range gd.Files(ctx) {
+	for _, err := range gd.Files(ctx) {
+		if err != nil {
+			return nil, fmt.Errorf("failed to list google drive files: %w", err)
+		}
+
 		found = true
 		break
 	}
@@ -110,8 +347,28 @@ func (c *Config) DriveFs(ctx context.Context) (fs *googledrive.GoogleDrive, err
 }
 
 var Example = Config{
-	Workers:  100,
-	Interval: 24 * time.Hour,
+	Workers:          100,
+	Interval:         24 * time.Hour,
+	Mirror:           false,
+	MaxDeletePercent: filesystem.DefaultMaxDeletePercent,
+	MaxFailures:      100,
+	Compare:          string(filesystem.CompareModTime),
+	State:            "/var/lib/drive2s3/journal",
+	Snapshot:         false,
+	Bandwidth: Bandwidth{
+		Schedule: []BandwidthWindow{
+			{
+				From:  "08:00",
+				To:    "18:00",
+				Days:  []string{"monday", "tuesday", "wednesday", "thursday", "friday"},
+				Limit: 10 * 1024 * 1024,
+			},
+		},
+	},
+	Filter: Filter{
+		Exclude:             []string{"~$*"},
+		ExcludeContentTypes: []string{"video/*"},
+	},
 	Drive: Drive{
 		AccountFile:    "/path/to/redacted/svc-account.json",
 		Subject:        "[REDACTED_ADMIN_EMAIL]",
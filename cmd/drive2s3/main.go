@@ -22,6 +22,7 @@ import (
 
 	"github.com/pluto-org-co/fsio/cmd/drive2s3/install"
 	"github.com/pluto-org-co/fsio/cmd/drive2s3/run"
+	"github.com/pluto-org-co/fsio/cmd/drive2s3/snapshot"
 	"github.com/urfave/cli/v3"
 )
 
@@ -30,6 +31,8 @@ var Drive2S3 = cli.Command{
 	Commands: []*cli.Command{
 		run.RunCommand,
 		install.InstallCommand,
+		snapshot.SnapshotsCommand,
+		snapshot.RestoreCommand,
 	},
 }
 
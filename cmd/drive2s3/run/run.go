@@ -17,19 +17,53 @@ package run
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"os"
+	"slices"
 	"time"
 
 	"github.com/pluto-org-co/fsio/filesystem"
+	"github.com/pluto-org-co/fsio/filesystem/journal"
 
 	"github.com/pluto-org-co/fsio/cmd/drive2s3/config"
 	"github.com/urfave/cli/v3"
-	"gopkg.in/yaml.v3"
 )
 
-var ConfigFlag = "config"
+var (
+	ConfigFlag = "config"
+	DryRunFlag = "dry-run"
+)
+
+// Minimum time between progress log lines
+const progressInterval = 30 * time.Second
+
+// Periodically logs the amount of files handled during a sync
+func progressLogger() (observer filesystem.Observer) {
+	var listed, done, failed, bytes int64
+	last := time.Now()
+	return func(event filesystem.Event) {
+		switch event.Kind {
+		case filesystem.EventListed:
+			listed++
+		case filesystem.EventCompleted, filesystem.EventSkipped:
+			done++
+			bytes += event.Bytes
+		case filesystem.EventFailed:
+			failed++
+		default:
+			return
+		}
+
+		if time.Since(last) < progressInterval {
+			return
+		}
+		last = time.Now()
+		log.Printf("Progress: %d/%d files, %d failed, %d bytes", done, listed, failed, bytes)
+	}
+}
 
 var RunCommand = &cli.Command{
 	Name:        "run",
@@ -39,21 +73,19 @@ var RunCommand = &cli.Command{
 			Name:  ConfigFlag,
 			Value: "config.yaml",
 		},
+		&cli.BoolFlag{
+			Name:  DryRunFlag,
+			Usage: "print the planned actions as JSON without writing anything",
+		},
 	},
 	Action: func(ctx context.Context, c *cli.Command) (err error) {
-		contents, err := os.ReadFile(c.String(ConfigFlag))
+		cfg, err := config.Load(c.String(ConfigFlag))
 		if err != nil {
-			return fmt.Errorf("failed to read file: %w", err)
-		}
-
-		var cfg config.Config
-		err = yaml.Unmarshal(contents, &cfg)
-		if err != nil {
-			return fmt.Errorf("failed to unmarshal contents: %w", err)
+			return fmt.Errorf("failed to load configuration: %w", err)
 		}
 
 		log.Println("Preparing S3 FS")
-		s3Fs, err := cfg.S3Fs(ctx)
+		backupFs, err := cfg.BackupFs(ctx)
 		if err != nil {
 			return fmt.Errorf("failed to prepare s3 fs: %w", err)
 		}
@@ -64,16 +96,84 @@ var RunCommand = &cli.Command{
 			return fmt.Errorf("failed to prepare drive fs: %w", err)
 		}
 
+		err = filesystem.ValidateSync(backupFs, driveFs)
+		if err != nil {
+			return fmt.Errorf("invalid sync configuration: %w", err)
+		}
+
+		syncOptions, err := cfg.SyncOptions()
+		if err != nil {
+			return fmt.Errorf("invalid sync configuration: %w", err)
+		}
+
+		if cfg.State != "" {
+			// Dry runs never write, not even the journal file
+			open := journal.Open
+			if c.Bool(DryRunFlag) {
+				open = journal.Load
+			}
+
+			state, err := open(cfg.State)
+			if err != nil {
+				return fmt.Errorf("failed to open sync state: %w", err)
+			}
+			defer state.Close()
+
+			syncOptions = append(syncOptions, filesystem.WithSyncOptionState(state))
+		}
+
+		if c.Bool(DryRunFlag) {
+			if cfg.Snapshot {
+				return errors.New("dry run is not supported in snapshot mode")
+			}
+
+			log.Println("Planning")
+			plan, planErr := filesystem.Plan(ctx, backupFs, driveFs, syncOptions...)
+
+			encoder := json.NewEncoder(os.Stdout)
+			encoder.SetIndent("", "  ")
+			err = encoder.Encode(plan)
+			if err != nil {
+				return fmt.Errorf("failed to encode plan: %w", err)
+			}
+
+			if planErr != nil {
+				return fmt.Errorf("incomplete plan: %w", planErr)
+			}
+			return nil
+		}
+
 		ticker := time.NewTicker(cfg.Interval)
 		defer ticker.Stop()
 
 		for {
-			log.Println("Syncing")
-			err = filesystem.SyncWorkers(cfg.Workers, ctx, s3Fs, driveFs)
+			options := append(slices.Clone(syncOptions), filesystem.WithSyncOptionObserver(progressLogger()))
+
+			var result *filesystem.SyncResult
+			if cfg.Snapshot {
+				log.Println("Snapshotting")
+				var manifest *filesystem.SnapshotManifest
+				manifest, result, err = filesystem.Snapshot(ctx, backupFs, driveFs, options...)
+				if err == nil {
+					log.Printf("Snapshot %s: %d files", manifest.ID, len(manifest.Entries))
+				}
+			} else {
+				log.Println("Syncing")
+				result, err = filesystem.Sync(ctx, backupFs, driveFs, options...)
+			}
+			for _, fileErr := range result.Errors {
+				log.Println(fileErr)
+			}
+			log.Printf("Synced in %s: %d copied, %d moved, %d deleted, %d skipped, %d failed, %d retried, %d bytes",
+				result.Duration, result.Copied, result.Moved, result.Deleted, result.Skipped, result.Failed, result.Retried, result.Bytes)
 			if err != nil {
 				return fmt.Errorf("failed to sync: %w", err)
 			}
 
+			if cfg.MaxFailures > 0 && result.Failed > cfg.MaxFailures {
+				return fmt.Errorf("too many failed files: %d > %d", result.Failed, cfg.MaxFailures)
+			}
+
 			log.Println("Waiting next sync")
 			<-ticker.C
 		}
@@ -0,0 +1,126 @@
+// Copyright (C) 2025 ZedCloud Org.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package snapshot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+
+	"github.com/pluto-org-co/fsio/cmd/drive2s3/config"
+	"github.com/pluto-org-co/fsio/cmd/drive2s3/run"
+	"github.com/pluto-org-co/fsio/filesystem"
+	"github.com/pluto-org-co/fsio/filesystem/directory"
+	"github.com/urfave/cli/v3"
+)
+
+var (
+	SnapshotFlag = "snapshot"
+	TargetFlag   = "target"
+)
+
+var SnapshotsCommand = &cli.Command{
+	Name:        "snapshots",
+	Description: "list the snapshots stored in the s3 bucket, oldest first",
+	Flags: []cli.Flag{
+		&cli.StringFlag{
+			Name:  run.ConfigFlag,
+			Value: "config.yaml",
+		},
+	},
+	Action: func(ctx context.Context, c *cli.Command) (err error) {
+		cfg, err := config.Load(c.String(run.ConfigFlag))
+		if err != nil {
+			return fmt.Errorf("failed to load configuration: %w", err)
+		}
+
+		backupFs, err := cfg.BackupFs(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to prepare s3 fs: %w", err)
+		}
+
+		ids, err := filesystem.Snapshots(ctx, backupFs)
+		if err != nil {
+			return fmt.Errorf("failed to list snapshots: %w", err)
+		}
+		for _, id := range ids {
+			fmt.Println(id)
+		}
+		return nil
+	},
+}
+
+var RestoreCommand = &cli.Command{
+	Name:        "restore",
+	Description: "restore a snapshot stored in the s3 bucket into a local directory",
+	Flags: []cli.Flag{
+		&cli.StringFlag{
+			Name:  run.ConfigFlag,
+			Value: "config.yaml",
+		},
+		&cli.StringFlag{
+			Name:  SnapshotFlag,
+			Usage: "snapshot to restore, defaults to the latest one",
+		},
+		&cli.StringFlag{
+			Name:     TargetFlag,
+			Usage:    "directory receiving the restored files",
+			Required: true,
+		},
+	},
+	Action: func(ctx context.Context, c *cli.Command) (err error) {
+		cfg, err := config.Load(c.String(run.ConfigFlag))
+		if err != nil {
+			return fmt.Errorf("failed to load configuration: %w", err)
+		}
+
+		backupFs, err := cfg.BackupFs(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to prepare s3 fs: %w", err)
+		}
+
+		id := c.String(SnapshotFlag)
+		if id == "" {
+			ids, err := filesystem.Snapshots(ctx, backupFs)
+			if err != nil {
+				return fmt.Errorf("failed to list snapshots: %w", err)
+			}
+			if len(ids) == 0 {
+				return errors.New("no snapshots found")
+			}
+			id = ids[len(ids)-1]
+		}
+
+		// The bandwidth is limited by the S3 client of the backup filesystem
+		options := []filesystem.SyncOption{filesystem.WithSyncOptionWorkers(cfg.Workers)}
+
+		log.Printf("Restoring snapshot %s", id)
+		target := directory.New(c.String(TargetFlag), 0o755, 0o644)
+		result, err := filesystem.Restore(ctx, target, backupFs, id, options...)
+		for _, fileErr := range result.Errors {
+			log.Println(fileErr)
+		}
+		log.Printf("Restored in %s: %d copied, %d failed, %d bytes", result.Duration, result.Copied, result.Failed, result.Bytes)
+		if err != nil {
+			return fmt.Errorf("failed to restore: %w", err)
+		}
+		if result.Failed > 0 {
+			return fmt.Errorf("failed to restore %d files", result.Failed)
+		}
+		return nil
+	},
+}
@@ -114,7 +114,11 @@ var Unshare = cli.Command{
 		{
 			logger := logger.With("mode", "drives")
 			logger.Info("Processing")
-			for driveEntry := range shareddrives.SeqDrives(ctx, rootDriveService) {
+			for driveEntry, err := range shareddrives.SeqDrives(ctx, rootDriveService) {
+				if err != nil {
+					return fmt.Errorf("failed to list drives: %w", err)
+				}
+
 				logger := logger.With("drive", driveEntry.Name)
 
 				logger.Info("Processing directories")
@@ -144,7 +148,12 @@ var Unshare = cli.Command{
 				}
 
 				logger.Info("Processing files")
-				for _, file := range shareddrives.SeqFiles(ctx, rootDriveService, driveEntry.Id) {
+				for entry, err := range shareddrives.SeqFiles(ctx, rootDriveService, driveEntry.Id) {
+					if err != nil {
+						return fmt.Errorf("failed to list drive files: %w", err)
+					}
+
+					file := entry.File
 					logger := logger.With("file", file.Name)
 
 					err = UnshareFile(workerPool, &wg, logger, rootDriveService, ctx, nil, file)
@@ -158,10 +167,18 @@ var Unshare = cli.Command{
 		{
 			logger := logger.With("mode", "users")
 			logger.Info("Processing")
-			for domain := range directory.SeqDomains(ctx, adminSvc) {
+			for domain, err := range directory.SeqDomains(ctx, adminSvc) {
+				if err != nil {
+					return fmt.Errorf("failed to list domains: %w", err)
+				}
+
 				logger := slog.With("domain", domain.DomainName)
 
-				for user := range directory.SeqUsers(ctx, adminSvc, domain.DomainName) {
+				for user, err := range directory.SeqUsers(ctx, adminSvc, domain.DomainName) {
+					if err != nil {
+						return fmt.Errorf("failed to list users: %w", err)
+					}
+
 					logger := logger.With("user", user.PrimaryEmail)
 					userDriveSvc, err := drive.NewService(ctx, option.WithHTTPClient(ClientFromSubject(ctx, config, user.PrimaryEmail)))
 					if err != nil {
@@ -190,7 +207,12 @@ var Unshare = cli.Command{
 					}
 
 					logger.Info("Processing Files")
-					for _, file := range drives.SeqFiles(ctx, userDriveSvc) {
+					for entry, err := range drives.SeqFiles(ctx, userDriveSvc) {
+						if err != nil {
+							return fmt.Errorf("failed to list user files: %w", err)
+						}
+
+						file := entry.File
 						logger := logger.With("file", file.Name)
 
 						err = UnshareFile(workerPool, &wg, logger, userDriveSvc, ctx, nil, file)
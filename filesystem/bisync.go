@@ -0,0 +1,379 @@
+// Copyright (C) 2025 ZedCloud Org.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package filesystem
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"maps"
+	"path"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Returned by BiSync when no sync state was passed for holding the baseline
+var ErrMissingBaseline = fmt.Errorf("missing baseline state: %w", fs.ErrInvalid)
+
+// How BiSync resolves the files modified on both sides since the baseline
+type ConflictPolicy int
+
+const (
+	// The most recently modified version replaces the other one
+	ConflictNewest ConflictPolicy = iota
+	// The most recently modified version keeps the location.
+	// The other version is kept on both sides under a suffixed name, like report.conflict-20060102-150405.txt
+	ConflictKeepBoth
+	// Both versions are left untouched and only reported in the result
+	ConflictReport
+)
+
+func WithSyncOptionConflict(policy ConflictPolicy) (option SyncOption) {
+	return func(ctx *SyncCtx) (err error) {
+		ctx.Conflict = policy
+		return nil
+	}
+}
+
+// Location keeping the losing version of a conflict
+func conflictLocation(location []string, modTime time.Time) (conflict []string) {
+	name := location[len(location)-1]
+	ext := path.Ext(name)
+	base := strings.TrimSuffix(name, ext)
+	if base == "" {
+		base, ext = name, ""
+	}
+
+	conflict = slices.Clone(location)
+	conflict[len(conflict)-1] = fmt.Sprintf("%s.conflict-%s%s", base, modTime.UTC().Format("20060102-150405"), ext)
+	return conflict
+}
+
+// Lists the files passing the filter
+func (s *SyncCtx) listFiles(ctx context.Context, fs Filesystem) (files map[string]FileEntry, err error) {
+	files = map[string]FileEntry{}
+	for entry, err := range fs.Files(ctx, s.ListOptions...) {
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrIncompleteListing, err)
+		}
+		if !s.Filter.Match(entry) {
+			continue
+		}
+		files[path.Join(entry.Location()...)] = entry
+	}
+	return files, nil
+}
+
+// Operation decided for a single location
+type biOperation struct {
+	location  []string
+	operation fileOperation
+	// Name of the side the operation removes the file from
+	removes string
+}
+
+type biSync struct {
+	ctx    *SyncCtx
+	a, b   Filesystem
+	result *SyncResult
+}
+
+// Copies the file listed in src into dst, recording it in the baseline
+func (s *biSync) copy(dst, src Filesystem, action ActionKind, entry FileEntry) (operation fileOperation) {
+	return func(ctx context.Context) (kind ActionKind, transferred int64, err error) {
+		transferred, err = transferFile(ctx, s.ctx, dst, src, action, entry.Location(), entry.ModTime())
+		if err == nil {
+			err = s.ctx.remember(action, entry, nil)
+		}
+		if err != nil {
+			return action, transferred, fmt.Errorf("failed to sync: %s: %w", path.Join(entry.Location()...), err)
+		}
+		return action, transferred, nil
+	}
+}
+
+// Removes the location from fs, forgetting it from the baseline
+func (s *biSync) remove(fs Filesystem, location []string) (operation fileOperation) {
+	return func(ctx context.Context) (kind ActionKind, transferred int64, err error) {
+		s.ctx.notify(Event{Kind: EventStarted, Action: ActionDelete, Location: location})
+
+		err = fs.RemoveAll(ctx, location)
+		if err == nil {
+			err = s.ctx.forget(location)
+		}
+		if err != nil {
+			return ActionDelete, 0, fmt.Errorf("failed to remove: %s: %w", path.Join(location...), err)
+		}
+		return ActionDelete, 0, nil
+	}
+}
+
+// Saves the loser version under the conflict location on both sides, then replaces it with the winner version
+func (s *biSync) keepBoth(winnerFs, loserFs Filesystem, winner, loser FileEntry) (operation fileOperation) {
+	return func(ctx context.Context) (kind ActionKind, transferred int64, err error) {
+		location := winner.Location()
+		conflict := conflictLocation(location, loser.ModTime())
+
+		steps := []struct {
+			dst, src                 Filesystem
+			kind                     ActionKind
+			dstLocation, srcLocation []string
+			modTime                  time.Time
+		}{
+			{winnerFs, loserFs, ActionCreate, conflict, location, loser.ModTime()},
+			{loserFs, loserFs, ActionCreate, conflict, location, loser.ModTime()},
+			{loserFs, winnerFs, ActionUpdate, location, location, winner.ModTime()},
+		}
+		for _, step := range steps {
+			copied, err := copyFile(ctx, s.ctx, step.dst, step.src, step.kind, step.dstLocation, step.srcLocation, step.modTime)
+			transferred += copied
+			if err != nil {
+				return ActionUpdate, transferred, fmt.Errorf("failed to keep both versions: %s: %w", path.Join(location...), err)
+			}
+		}
+
+		err = errors.Join(
+			s.ctx.State.Put(conflict, recordOf(loser)),
+			s.ctx.State.Put(location, recordOf(winner)),
+		)
+		if err != nil {
+			return ActionUpdate, transferred, fmt.Errorf("failed to record sync state: %w", err)
+		}
+		return ActionUpdate, transferred, nil
+	}
+}
+
+// Records the location as agreed by both sides
+func (s *biSync) agree(entry FileEntry) (operation fileOperation) {
+	return func(ctx context.Context) (kind ActionKind, transferred int64, err error) {
+		err = s.ctx.remember(ActionSkip, entry, nil)
+		if err != nil {
+			return ActionSkip, 0, fmt.Errorf("failed to skip: %s: %w", path.Join(entry.Location()...), err)
+		}
+		return ActionSkip, 0, nil
+	}
+}
+
+// Resolves the location modified on both sides
+func (s *biSync) resolve(aEntry, bEntry FileEntry) (op *biOperation) {
+	location := aEntry.Location()
+	s.result.Conflicts = append(s.result.Conflicts, location)
+
+	winnerFs, winner, loserFs, loser := s.a, aEntry, s.b, bEntry
+	if bEntry.ModTime().After(aEntry.ModTime()) {
+		winnerFs, winner, loserFs, loser = s.b, bEntry, s.a, aEntry
+	}
+
+	switch s.ctx.Conflict {
+	case ConflictKeepBoth:
+		return &biOperation{location: location, operation: s.keepBoth(winnerFs, loserFs, winner, loser)}
+	case ConflictReport:
+		return nil
+	default:
+		return &biOperation{location: location, operation: s.copy(loserFs, winnerFs, ActionUpdate, winner)}
+	}
+}
+
+// Decides the operation bringing both sides to agree on the location. Nil when there is nothing to do
+func (s *biSync) decide(aEntry, bEntry FileEntry) (op *biOperation) {
+	var location []string
+	if aEntry != nil {
+		location = aEntry.Location()
+	} else if bEntry != nil {
+		location = bEntry.Location()
+	}
+
+	record, inBaseline := s.ctx.State.Get(location)
+	changed := func(entry FileEntry) (ok bool) {
+		return !inBaseline || !record.Matches(entry)
+	}
+
+	switch {
+	case aEntry != nil && bEntry != nil:
+		aChanged, bChanged := changed(aEntry), changed(bEntry)
+		switch {
+		case !aChanged && !bChanged:
+			return nil
+		case aChanged && !bChanged:
+			return &biOperation{location: location, operation: s.copy(s.b, s.a, ActionUpdate, aEntry)}
+		case !aChanged && bChanged:
+			return &biOperation{location: location, operation: s.copy(s.a, s.b, ActionUpdate, bEntry)}
+		case sameModTime(aEntry.ModTime(), bEntry.ModTime()) && aEntry.Size() == bEntry.Size():
+			return &biOperation{location: location, operation: s.agree(aEntry)}
+		default:
+			return s.resolve(aEntry, bEntry)
+		}
+	case aEntry != nil:
+		return s.decideSingle(location, inBaseline, changed(aEntry), aEntry, "a", s.a, s.b)
+	case bEntry != nil:
+		return s.decideSingle(location, inBaseline, changed(bEntry), bEntry, "b", s.b, s.a)
+	default:
+		return nil
+	}
+}
+
+// Decides the operation for the location only found in the named side
+func (s *biSync) decideSingle(location []string, inBaseline, changed bool, entry FileEntry, side string, fs, other Filesystem) (op *biOperation) {
+	switch {
+	case !inBaseline:
+		return &biOperation{location: location, operation: s.copy(other, fs, ActionCreate, entry)}
+	case !changed:
+		return &biOperation{location: location, operation: s.remove(fs, location), removes: side}
+	default:
+		// Modified on one side and removed from the other, the modification wins
+		s.result.Conflicts = append(s.result.Conflicts, location)
+		if s.ctx.Conflict == ConflictReport {
+			return nil
+		}
+		return &biOperation{location: location, operation: s.copy(other, fs, ActionCreate, entry)}
+	}
+}
+
+// Forgets the baseline of the locations in the sync scope removed from both sides,
+// so files created later at the same location are not mistaken for modifications
+func (s *biSync) forgetRemoved(aFiles, bFiles map[string]FileEntry) (err error) {
+	listCtx := NewListCtx(s.ctx.ListOptions...)
+	for _, location := range s.ctx.State.Locations() {
+		key := path.Join(location...)
+		_, inA := aFiles[key]
+		_, inB := bFiles[key]
+		if inA || inB || !listCtx.Match(location) {
+			continue
+		}
+
+		record, found := s.ctx.State.Get(location)
+		if !found {
+			continue
+		}
+		entry := &SimpleFileEntry{LocationValue: location, ModTimeValue: record.ModTime, SizeValue: record.Size}
+		if !s.ctx.Filter.Match(entry) {
+			continue
+		}
+
+		err = s.ctx.forget(location)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Propagates the changes done on each side since the baseline recorded in the sync state to the other side.
+// Files created or modified on a single side are copied to the other one, files removed from a single side
+// are removed from the other one. Files modified on both sides are resolved following the conflict policy,
+// and modifications always win over removals.
+// The State option is required for holding the baseline. Nothing is written unless both listings complete.
+// Modification times are always preserved, and the filter applies to both sides
+func BiSync(ctx context.Context, a, b Filesystem, options ...SyncOption) (result *SyncResult, err error) {
+	syncCtx := NewSyncCtx(options...)
+
+	start := time.Now()
+	result = &SyncResult{}
+	defer func() {
+		result.Duration = time.Since(start)
+		syncCtx.notify(Event{Kind: EventFinished, Err: err, Result: result})
+	}()
+
+	if syncCtx.State == nil {
+		return result, ErrMissingBaseline
+	}
+	err = RequireCapabilities(a, CapabilityRead|CapabilityWrite|CapabilityRemove)
+	if err != nil {
+		return result, fmt.Errorf("invalid a: %w", err)
+	}
+	err = RequireCapabilities(b, CapabilityRead|CapabilityWrite|CapabilityRemove)
+	if err != nil {
+		return result, fmt.Errorf("invalid b: %w", err)
+	}
+
+	aFiles, err := syncCtx.listFiles(ctx, a)
+	if err != nil {
+		return result, fmt.Errorf("failed to list a files: %w", err)
+	}
+	bFiles, err := syncCtx.listFiles(ctx, b)
+	if err != nil {
+		return result, fmt.Errorf("failed to list b files: %w", err)
+	}
+
+	keys := slices.Collect(maps.Keys(aFiles))
+	for key := range bFiles {
+		_, found := aFiles[key]
+		if !found {
+			keys = append(keys, key)
+		}
+	}
+	slices.Sort(keys)
+
+	s := &biSync{ctx: syncCtx, a: a, b: b, result: result}
+	var (
+		operations []*biOperation
+		removals   = map[string]int{}
+	)
+	for _, key := range keys {
+		aEntry, bEntry := aFiles[key], bFiles[key]
+		for _, entry := range []FileEntry{aEntry, bEntry} {
+			if entry != nil {
+				syncCtx.notify(Event{Kind: EventListed, Location: entry.Location(), Bytes: entry.Size()})
+			}
+		}
+
+		op := s.decide(aEntry, bEntry)
+		if op == nil {
+			continue
+		}
+		if op.removes != "" {
+			removals[op.removes]++
+		}
+		operations = append(operations, op)
+	}
+
+	for side, files := range map[string]int{"a": len(aFiles), "b": len(bFiles)} {
+		if removals[side] == 0 {
+			continue
+		}
+		percent := 100 * float64(removals[side]) / float64(files)
+		if percent > syncCtx.MaxDeletePercent {
+			return result, fmt.Errorf("refusing to remove %d of %d %s files (%.1f%% > %.1f%%): %w",
+				removals[side], files, side, percent, syncCtx.MaxDeletePercent, ErrTooManyDeletions)
+		}
+	}
+
+	err = s.forgetRemoved(aFiles, bFiles)
+	if err != nil {
+		return result, err
+	}
+
+	pool := newWorkerPool(ctx, syncCtx, result)
+	defer pool.Close()
+
+	for _, op := range operations {
+		if !pool.Go(op.location, op.operation) {
+			break
+		}
+	}
+
+	err = pool.Wait()
+	switch {
+	case err != nil:
+		return result, err
+	case ctx.Err() != nil:
+		return result, fmt.Errorf("failed to sync due to context error: %w", ctx.Err())
+	default:
+		return result, nil
+	}
+}
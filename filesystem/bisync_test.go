@@ -0,0 +1,253 @@
+// Copyright (C) 2025 ZedCloud Org.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package filesystem_test
+
+import (
+	"context"
+	"io"
+	"os"
+	"path"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pluto-org-co/fsio/filesystem"
+	"github.com/pluto-org-co/fsio/filesystem/directory"
+	"github.com/pluto-org-co/fsio/filesystem/journal"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_BiSync(t *testing.T) {
+	if os.Getuid() == 0 {
+		t.Skip("Can't run this test as root")
+		return
+	}
+
+	modTime := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	newSides := func(t *testing.T) (a, b filesystem.Filesystem, state *journal.Journal, ok bool) {
+		state, err := journal.Open(path.Join(t.TempDir(), "journal"))
+		if !assert.Nil(t, err, "failed to open journal") {
+			return nil, nil, nil, false
+		}
+		t.Cleanup(func() { state.Close() })
+		return directory.New(t.TempDir(), 0o777, 0o777), directory.New(t.TempDir(), 0o777, 0o777), state, true
+	}
+	write := func(t *testing.T, fs filesystem.Filesystem, name, contents string, modTime time.Time) (ok bool) {
+		_, err := fs.WriteFile(context.TODO(), []string{"docs", name}, strings.NewReader(contents), modTime)
+		return assert.Nil(t, err, "failed to write file")
+	}
+	read := func(t *testing.T, fs filesystem.Filesystem, location ...string) (contents string) {
+		file, err := fs.Open(context.TODO(), location)
+		if !assert.Nil(t, err, "failed to open file") {
+			return ""
+		}
+		defer file.Close()
+
+		raw, err := io.ReadAll(file)
+		assert.Nil(t, err, "failed to read file")
+		return string(raw)
+	}
+
+	t.Run("Propagate", func(t *testing.T) {
+		assertions := assert.New(t)
+
+		ctx, cancel := context.WithTimeout(context.TODO(), time.Minute)
+		defer cancel()
+
+		a, b, state, ok := newSides(t)
+		if !ok {
+			return
+		}
+		options := []filesystem.SyncOption{filesystem.WithSyncOptionState(state), filesystem.WithSyncOptionMaxDeletePercent(100)}
+
+		for _, name := range []string{"first.txt", "second.txt"} {
+			if !write(t, a, name, name, modTime) {
+				return
+			}
+		}
+		if !write(t, b, "third.txt", "third.txt", modTime) {
+			return
+		}
+
+		result, err := filesystem.BiSync(ctx, a, b, options...)
+		if !assertions.Nil(err, "failed to sync files") {
+			return
+		}
+		assertions.Equal(int64(3), result.Copied, "new files should be copied to the other side")
+		assertions.Equal("third.txt", read(t, a, "docs", "third.txt"), "b files should reach a")
+		assertions.Equal("first.txt", read(t, b, "docs", "first.txt"), "a files should reach b")
+
+		// Changes done on each side after the first sync
+		if !write(t, b, "first.txt", "updated", modTime.Add(time.Hour)) {
+			return
+		}
+		err = a.RemoveAll(ctx, []string{"docs", "second.txt"})
+		if !assertions.Nil(err, "failed to remove file") {
+			return
+		}
+
+		result, err = filesystem.BiSync(ctx, a, b, options...)
+		if !assertions.Nil(err, "failed to sync files") {
+			return
+		}
+		assertions.Equal(int64(1), result.Copied, "modified file should be copied")
+		assertions.Equal(int64(1), result.Deleted, "removed file should be removed")
+		assertions.Empty(result.Conflicts, "no conflict expected")
+		assertions.Equal("updated", read(t, a, "docs", "first.txt"), "b modification should reach a")
+		_, err = b.Stat(ctx, []string{"docs", "second.txt"})
+		assertions.ErrorIs(err, os.ErrNotExist, "a removal should reach b")
+
+		result, err = filesystem.BiSync(ctx, a, b, options...)
+		if !assertions.Nil(err, "failed to sync files") {
+			return
+		}
+		assertions.Zero(result.Copied+result.Deleted, "agreed sides should be left untouched")
+	})
+
+	t.Run("RemovedFromBoth", func(t *testing.T) {
+		assertions := assert.New(t)
+
+		ctx, cancel := context.WithTimeout(context.TODO(), time.Minute)
+		defer cancel()
+
+		a, b, state, ok := newSides(t)
+		if !ok {
+			return
+		}
+		options := []filesystem.SyncOption{
+			filesystem.WithSyncOptionState(state),
+			filesystem.WithSyncOptionConflict(filesystem.ConflictReport),
+			filesystem.WithSyncOptionMaxDeletePercent(100),
+		}
+
+		for _, name := range []string{"kept.txt", "removed.txt"} {
+			if !write(t, a, name, name, modTime) {
+				return
+			}
+		}
+		_, err := filesystem.BiSync(ctx, a, b, options...)
+		if !assertions.Nil(err, "failed to sync files") {
+			return
+		}
+
+		for _, fs := range []filesystem.Filesystem{a, b} {
+			err = fs.RemoveAll(ctx, []string{"docs", "removed.txt"})
+			if !assertions.Nil(err, "failed to remove file") {
+				return
+			}
+		}
+		_, err = filesystem.BiSync(ctx, a, b, options...)
+		if !assertions.Nil(err, "failed to sync files") {
+			return
+		}
+		_, found := state.Get([]string{"docs", "removed.txt"})
+		assertions.False(found, "files removed from both sides should be forgotten")
+
+		// New file created at the same location
+		if !write(t, b, "removed.txt", "new file", modTime.Add(time.Hour)) {
+			return
+		}
+		result, err := filesystem.BiSync(ctx, a, b, options...)
+		if !assertions.Nil(err, "failed to sync files") {
+			return
+		}
+		assertions.Empty(result.Conflicts, "new files should not conflict")
+		assertions.Equal(int64(1), result.Copied, "new file should be copied")
+		assertions.Equal("new file", read(t, a, "docs", "removed.txt"), "new file should reach a")
+	})
+
+	type Test struct {
+		Name   string
+		Policy filesystem.ConflictPolicy
+		Check  func(t *testing.T, a, b filesystem.Filesystem, result *filesystem.SyncResult)
+	}
+	tests := []Test{
+		{
+			Name:   "Newest",
+			Policy: filesystem.ConflictNewest,
+			Check: func(t *testing.T, a, b filesystem.Filesystem, result *filesystem.SyncResult) {
+				assert.Equal(t, "b version", read(t, a, "docs", "file.txt"), "newest version should win")
+			},
+		},
+		{
+			Name:   "KeepBoth",
+			Policy: filesystem.ConflictKeepBoth,
+			Check: func(t *testing.T, a, b filesystem.Filesystem, result *filesystem.SyncResult) {
+				conflict := "file.conflict-" + modTime.Add(time.Hour).Format("20060102-150405") + ".txt"
+				for _, fs := range []filesystem.Filesystem{a, b} {
+					assert.Equal(t, "b version", read(t, fs, "docs", "file.txt"), "newest version should keep the location")
+					assert.Equal(t, "a version", read(t, fs, "docs", conflict), "older version should be kept")
+				}
+			},
+		},
+		{
+			Name:   "Report",
+			Policy: filesystem.ConflictReport,
+			Check: func(t *testing.T, a, b filesystem.Filesystem, result *filesystem.SyncResult) {
+				assert.Equal(t, "a version", read(t, a, "docs", "file.txt"), "a should be untouched")
+				assert.Equal(t, "b version", read(t, b, "docs", "file.txt"), "b should be untouched")
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			assertions := assert.New(t)
+
+			ctx, cancel := context.WithTimeout(context.TODO(), time.Minute)
+			defer cancel()
+
+			a, b, state, ok := newSides(t)
+			if !ok {
+				return
+			}
+			options := []filesystem.SyncOption{filesystem.WithSyncOptionState(state), filesystem.WithSyncOptionConflict(test.Policy)}
+
+			if !write(t, a, "file.txt", "original", modTime) {
+				return
+			}
+			_, err := filesystem.BiSync(ctx, a, b, options...)
+			if !assertions.Nil(err, "failed to sync files") {
+				return
+			}
+
+			if !write(t, a, "file.txt", "a version", modTime.Add(time.Hour)) {
+				return
+			}
+			if !write(t, b, "file.txt", "b version", modTime.Add(2*time.Hour)) {
+				return
+			}
+
+			result, err := filesystem.BiSync(ctx, a, b, options...)
+			if !assertions.Nil(err, "failed to sync files") {
+				return
+			}
+			assertions.Equal([][]string{{"docs", "file.txt"}}, result.Conflicts, "conflict should be reported")
+			test.Check(t, a, b, result)
+		})
+	}
+
+	t.Run("MissingBaseline", func(t *testing.T) {
+		assertions := assert.New(t)
+
+		a, b, _, ok := newSides(t)
+		if !ok {
+			return
+		}
+		_, err := filesystem.BiSync(context.TODO(), a, b)
+		assertions.ErrorIs(err, filesystem.ErrMissingBaseline, "baseline should be required")
+	})
+}
@@ -0,0 +1,156 @@
+// Copyright (C) 2025 ZedCloud Org.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package filesystem
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+type Reader interface {
+	// Opens a reader for the passed file.
+	Open(ctx context.Context, location []string) (rc io.ReadCloser, err error)
+}
+
+type Writer interface {
+	// Writes the reader to the dst filePath.
+	// Returned filename is the actual name used during the write. Done this way since some implementation may alter the file name during
+	// normalization
+	WriteFile(ctx context.Context, location []string, src io.Reader, modTime time.Time) (finalLocation []string, err error)
+}
+
+type Remover interface {
+	// Remove the file or every file under the location from the filesystem.
+	// Removing a missing location succeeds
+	RemoveAll(ctx context.Context, location []string) (err error)
+}
+
+type Mover interface {
+	// Move a file to a new location
+	Move(ctx context.Context, oldLocation, newLocation []string) (finalLocation []string, err error)
+}
+
+// Implemented by filesystems able to read a portion of a file.
+// Use OpenRange for falling back to Open when it isn't implemented
+type RangeReader interface {
+	// Opens a reader for length bytes of the passed file starting at offset.
+	// A negative length reads until the end of the file
+	OpenRange(ctx context.Context, location []string, offset, length int64) (rc io.ReadCloser, err error)
+}
+
+// Writer returned by Creator. Close commits the file and Abort discards it
+type FileWriter interface {
+	io.WriteCloser
+	// Discards the contents written so far. Calling Close after Abort does nothing
+	Abort() (err error)
+	// Location used for the file, which may differ from the requested one due to normalization.
+	// Only valid after Close succeeds
+	Location() (location []string)
+}
+
+// Implemented by filesystems able to stream the contents of a file while it is being written.
+// Use Create for falling back to WriteFile when it isn't implemented
+type Creator interface {
+	// Creates a writer for the passed file. The file is only available after Close succeeds
+	Create(ctx context.Context, location []string, modTime time.Time) (w FileWriter, err error)
+}
+
+type Capability uint
+
+const (
+	CapabilityRead Capability = 1 << iota
+	CapabilityWrite
+	CapabilityRemove
+	CapabilityMove
+	// Ranged reads don't transfer the skipped contents
+	CapabilityRangeRead
+)
+
+var capabilityNames = []struct {
+	capability Capability
+	name       string
+}{
+	{CapabilityRead, "read"},
+	{CapabilityWrite, "write"},
+	{CapabilityRemove, "remove"},
+	{CapabilityMove, "move"},
+	{CapabilityRangeRead, "range-read"},
+}
+
+// Reports if all the passed capabilities are present
+func (c Capability) Has(other Capability) (ok bool) {
+	return c&other == other
+}
+
+func (c Capability) String() (s string) {
+	var names = make([]string, 0, len(capabilityNames))
+	for _, entry := range capabilityNames {
+		if c.Has(entry.capability) {
+			names = append(names, entry.name)
+		}
+	}
+	if len(names) == 0 {
+		return "none"
+	}
+	return strings.Join(names, "|")
+}
+
+// Implemented by filesystems that can't honor every method of the Filesystem interface.
+// For example read-only backends.
+type CapabilitiesReporter interface {
+	Capabilities() (caps Capability)
+}
+
+// Returns the capabilities of the filesystem.
+// Filesystems not implementing CapabilitiesReporter are assumed to support every operation they implement
+func Capabilities(fs Filesystem) (caps Capability) {
+	reporter, ok := fs.(CapabilitiesReporter)
+	if ok {
+		return reporter.Capabilities()
+	}
+
+	caps = CapabilityRead | CapabilityWrite | CapabilityRemove | CapabilityMove
+	if _, ok := fs.(RangeReader); ok {
+		caps |= CapabilityRangeRead
+	}
+	return caps
+}
+
+// Fails with ErrUnsupported if the filesystem is missing any of the required capabilities
+func RequireCapabilities(fs Filesystem, required Capability) (err error) {
+	missing := required &^ Capabilities(fs)
+	if missing != 0 {
+		return fmt.Errorf("missing capabilities: %s: %w", missing, ErrUnsupported)
+	}
+	return nil
+}
+
+// Verifies the pair of filesystems can be used for copying or syncing from src into dst
+func ValidateSync(dst, src Filesystem) (err error) {
+	err = RequireCapabilities(src, CapabilityRead)
+	if err != nil {
+		return fmt.Errorf("invalid src: %w", err)
+	}
+
+	err = RequireCapabilities(dst, CapabilityWrite)
+	if err != nil {
+		return fmt.Errorf("invalid dst: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,70 @@
+// Copyright (C) 2025 ZedCloud Org.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package filesystem_test
+
+import (
+	"compress/gzip"
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/pluto-org-co/fsio/filesystem"
+	"github.com/pluto-org-co/fsio/filesystem/googledrive"
+	"github.com/pluto-org-co/fsio/filesystem/gzipfs"
+	"github.com/pluto-org-co/fsio/filesystem/randomfs"
+	"github.com/pluto-org-co/fsio/filesystem/testsuite"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Capabilities(t *testing.T) {
+	t.Run("Writable", func(t *testing.T) {
+		assertions := assert.New(t)
+
+		randomFs := randomfs.New(testsuite.GenerateLocations(10), 1024)
+		gzipFs := gzipfs.New(gzip.BestSpeed, randomFs)
+
+		for _, fs := range []filesystem.Filesystem{randomFs, gzipFs} {
+			caps := filesystem.Capabilities(fs)
+			t.Logf("Capabilities: %s", caps)
+
+			assertions.True(caps.Has(filesystem.CapabilityRead|filesystem.CapabilityWrite), "should be able to read and write")
+			assertions.Nil(filesystem.ValidateSync(fs, fs), "should be valid for syncing")
+		}
+	})
+	t.Run("ReadOnly", func(t *testing.T) {
+		assertions := assert.New(t)
+
+		driveFs := googledrive.New(googledrive.Config{})
+		randomFs := randomfs.New(testsuite.GenerateLocations(10), 1024)
+
+		caps := filesystem.Capabilities(driveFs)
+		assertions.True(caps.Has(filesystem.CapabilityRead), "should be readable")
+		assertions.False(caps.Has(filesystem.CapabilityWrite), "should not be writable")
+
+		assertions.Nil(filesystem.ValidateSync(randomFs, driveFs), "should be valid as src")
+
+		err := filesystem.ValidateSync(driveFs, randomFs)
+		assertions.True(errors.Is(err, filesystem.ErrUnsupported), "should not be valid as dst")
+		assertions.True(errors.Is(err, errors.ErrUnsupported), "should wrap errors.ErrUnsupported")
+
+		ctx, cancel := context.WithTimeout(context.TODO(), time.Minute)
+		defer cancel()
+
+		_, err = driveFs.WriteFile(ctx, []string{"file"}, nil, time.Now())
+		assertions.True(errors.Is(err, errors.ErrUnsupported), "write should be unsupported")
+	})
+}
@@ -0,0 +1,131 @@
+// Copyright (C) 2025 ZedCloud Org.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package filesystem
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// How the sync decides that dst already holds the src version of a file
+type CompareStrategy string
+
+const (
+	// Same modification time, to the second
+	CompareModTime CompareStrategy = "modtime"
+	// Same modification time, to the second, and same size.
+	// Falls back to the modification time alone when either size is unknown, like Google Workspace documents
+	CompareModTimeSize CompareStrategy = "modtime-size"
+	// Same ChecksumSha256 of the contents. Reads both files for every comparison
+	CompareSha256 CompareStrategy = "sha256"
+	// Same hash reported by the backends in the FileInfo ETag. Files are always copied when either backend reports none.
+	// Only meaningful between backends reporting the same kind of hash, like the Google Drive md5 and the S3 ETag of single part uploads
+	CompareETag CompareStrategy = "etag"
+)
+
+var compareStrategies = []CompareStrategy{CompareModTime, CompareModTimeSize, CompareSha256, CompareETag}
+
+// Returns the strategy with the passed name
+func ParseCompareStrategy(name string) (strategy CompareStrategy, err error) {
+	for _, strategy := range compareStrategies {
+		if string(strategy) == name {
+			return strategy, nil
+		}
+	}
+	return "", fmt.Errorf("unknown compare strategy: %q", name)
+}
+
+// Whether the strategy needs more than the listed modification time and size
+func (c CompareStrategy) needsStat() (ok bool) {
+	return c == CompareETag
+}
+
+func (c CompareStrategy) describe() (description string) {
+	switch c {
+	case CompareModTimeSize:
+		return "modification time and size"
+	case CompareSha256:
+		return "sha256 checksum"
+	case CompareETag:
+		return "etag"
+	default:
+		return "modification time"
+	}
+}
+
+// Backends store modification times with different precisions, S3 keeps whole seconds
+func sameModTime(a, b time.Time) (ok bool) {
+	return a.Truncate(time.Second).Equal(b.Truncate(time.Second))
+}
+
+// Reports if dst already holds the same version of the src file
+func (s *SyncCtx) unchanged(ctx context.Context, dst, src Filesystem, srcInfo, dstInfo *FileInfo) (ok bool, err error) {
+	if srcInfo == nil || dstInfo == nil {
+		return false, nil
+	}
+
+	switch s.Compare {
+	case CompareModTimeSize:
+		if srcInfo.Size < 0 || dstInfo.Size < 0 {
+			return sameModTime(srcInfo.ModTime, dstInfo.ModTime), nil
+		}
+		return sameModTime(srcInfo.ModTime, dstInfo.ModTime) && srcInfo.Size == dstInfo.Size, nil
+	case CompareSha256:
+		srcChecksum, err := src.ChecksumSha256(ctx, srcInfo.Location)
+		if err != nil {
+			return false, fmt.Errorf("failed to compute src checksum: %w", srcError(err))
+		}
+		dstChecksum, err := dst.ChecksumSha256(ctx, dstInfo.Location)
+		if err != nil {
+			return false, fmt.Errorf("failed to compute dst checksum: %w", err)
+		}
+		return srcChecksum == dstChecksum, nil
+	case CompareETag:
+		srcTag := strings.Trim(srcInfo.ETag, `"`)
+		dstTag := strings.Trim(dstInfo.ETag, `"`)
+		return srcTag != "" && strings.EqualFold(srcTag, dstTag), nil
+	default:
+		return sameModTime(srcInfo.ModTime, dstInfo.ModTime), nil
+	}
+}
+
+// Compares the listed entries, asking the backends for the metadata the strategy can't find in the listings
+func (s *SyncCtx) unchangedEntries(ctx context.Context, dst, src Filesystem, srcEntry, dstEntry FileEntry) (ok bool, err error) {
+	srcInfo, dstInfo := infoOf(srcEntry), infoOf(dstEntry)
+	if s.Compare.needsStat() {
+		srcInfo, err = src.Stat(ctx, srcEntry.Location())
+		if err != nil {
+			return false, fmt.Errorf("failed to stat src file: %w", srcError(err))
+		}
+		dstInfo, err = dst.Stat(ctx, dstEntry.Location())
+		if err != nil {
+			return false, fmt.Errorf("failed to stat dst file: %w", err)
+		}
+	}
+	return s.unchanged(ctx, dst, src, srcInfo, dstInfo)
+}
+
+// Metadata of the listed entry
+func infoOf(entry FileEntry) (info *FileInfo) {
+	return &FileInfo{
+		Location:    entry.Location(),
+		Size:        entry.Size(),
+		ModTime:     entry.ModTime(),
+		ContentType: entry.ContentType(),
+	}
+}
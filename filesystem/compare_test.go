@@ -0,0 +1,145 @@
+// Copyright (C) 2025 ZedCloud Org.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package filesystem_test
+
+import (
+	"context"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pluto-org-co/fsio/filesystem"
+	"github.com/pluto-org-co/fsio/filesystem/directory"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Compare(t *testing.T) {
+	if os.Getuid() == 0 {
+		t.Skip("Can't run this test as root")
+		return
+	}
+
+	modTime := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	location := []string{"docs", "file.txt"}
+
+	type Test struct {
+		Name        string
+		Strategy    filesystem.CompareStrategy
+		SrcContents string
+		SrcModTime  time.Time
+		Expect      filesystem.ActionKind
+	}
+	tests := []Test{
+		{Name: "ModTime Same", Strategy: filesystem.CompareModTime, SrcContents: "contents", SrcModTime: modTime, Expect: filesystem.ActionSkip},
+		{Name: "ModTime Same Minute", Strategy: filesystem.CompareModTime, SrcContents: "contents", SrcModTime: modTime.Add(10 * time.Second), Expect: filesystem.ActionUpdate},
+		{Name: "ModTime Same Second", Strategy: filesystem.CompareModTime, SrcContents: "changed contents", SrcModTime: modTime.Add(500 * time.Millisecond), Expect: filesystem.ActionSkip},
+		{Name: "ModTimeSize Same", Strategy: filesystem.CompareModTimeSize, SrcContents: "contents", SrcModTime: modTime, Expect: filesystem.ActionSkip},
+		{Name: "ModTimeSize Different Size", Strategy: filesystem.CompareModTimeSize, SrcContents: "changed contents", SrcModTime: modTime, Expect: filesystem.ActionUpdate},
+		{Name: "Sha256 Same Contents", Strategy: filesystem.CompareSha256, SrcContents: "contents", SrcModTime: modTime.Add(time.Hour), Expect: filesystem.ActionSkip},
+		{Name: "Sha256 Different Contents", Strategy: filesystem.CompareSha256, SrcContents: "CONTENTS", SrcModTime: modTime, Expect: filesystem.ActionUpdate},
+		{Name: "ETag Unsupported", Strategy: filesystem.CompareETag, SrcContents: "contents", SrcModTime: modTime, Expect: filesystem.ActionUpdate},
+	}
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			assertions := assert.New(t)
+
+			ctx, cancel := context.WithTimeout(context.TODO(), time.Minute)
+			defer cancel()
+
+			src := directory.New(t.TempDir(), 0o777, 0o777)
+			dst := directory.New(t.TempDir(), 0o777, 0o777)
+
+			_, err := dst.WriteFile(ctx, location, strings.NewReader("contents"), modTime)
+			if !assertions.Nil(err, "failed to write dst file") {
+				return
+			}
+			_, err = src.WriteFile(ctx, location, strings.NewReader(test.SrcContents), test.SrcModTime)
+			if !assertions.Nil(err, "failed to write src file") {
+				return
+			}
+
+			option := filesystem.WithSyncOptionCompare(test.Strategy)
+
+			plan, err := filesystem.Plan(ctx, dst, src, option)
+			if !assertions.Nil(err, "failed to plan sync") {
+				return
+			}
+			if !assertions.Len(plan.Actions, 1, "expecting a single action") {
+				return
+			}
+			assertions.Equal(test.Expect, plan.Actions[0].Kind, "invalid planned action")
+
+			result, err := filesystem.Sync(ctx, dst, src, option)
+			if !assertions.Nil(err, "failed to sync files") {
+				return
+			}
+			if test.Expect == filesystem.ActionSkip {
+				assertions.Equal(int64(1), result.Skipped, "file should be skipped")
+			} else {
+				assertions.Equal(int64(1), result.Copied, "file should be copied")
+			}
+		})
+	}
+
+	t.Run("ModTimeSize Unknown Size", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.TODO(), time.Minute)
+		defer cancel()
+
+		dst := directory.New(t.TempDir(), 0o777, 0o777)
+		_, err := dst.WriteFile(ctx, location, strings.NewReader("contents"), modTime)
+		if !assert.Nil(t, err, "failed to write dst file") {
+			return
+		}
+
+		// Like Google Workspace documents, listed without size
+		for name, srcModTime := range map[string]time.Time{"Same ModTime": modTime, "Different ModTime": modTime.Add(time.Hour)} {
+			t.Run(name, func(t *testing.T) {
+				assertions := assert.New(t)
+
+				src := directory.New(t.TempDir(), 0o777, 0o777)
+				_, err := src.WriteFile(ctx, location, strings.NewReader("changed contents"), srcModTime)
+				if !assertions.Nil(err, "failed to write src file") {
+					return
+				}
+
+				plan, err := filesystem.Plan(ctx, dst, &unknownSizes{Filesystem: src}, filesystem.WithSyncOptionCompare(filesystem.CompareModTimeSize))
+				if !assertions.Nil(err, "failed to plan sync") {
+					return
+				}
+				if !assertions.Len(plan.Actions, 1, "expecting a single action") {
+					return
+				}
+
+				expect := filesystem.ActionUpdate
+				if srcModTime.Equal(modTime) {
+					expect = filesystem.ActionSkip
+				}
+				assertions.Equal(expect, plan.Actions[0].Kind, "unknown sizes should only compare the modification time")
+			})
+		}
+	})
+	t.Run("Parse", func(t *testing.T) {
+		assertions := assert.New(t)
+
+		strategy, err := filesystem.ParseCompareStrategy("sha256")
+		assertions.Nil(err, "failed to parse strategy")
+		assertions.Equal(filesystem.CompareSha256, strategy, "invalid strategy")
+
+		_, err = filesystem.ParseCompareStrategy("unknown")
+		assertions.NotNil(err, "unknown strategies should fail")
+	})
+}
@@ -0,0 +1,144 @@
+// Copyright (C) 2025 ZedCloud Org.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package filesystem
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/pluto-org-co/fsio/ioutils"
+)
+
+var errAborted = errors.New("write aborted")
+
+// Creates a writer for the passed file.
+// Filesystems not implementing Creator fallback to a WriteFile fed by the returned writer
+func Create(ctx context.Context, writer Writer, location []string, modTime time.Time) (w FileWriter, err error) {
+	reporter, ok := writer.(CapabilitiesReporter)
+	if ok && !reporter.Capabilities().Has(CapabilityWrite) {
+		return nil, fmt.Errorf("failed to create file: %w", ErrUnsupported)
+	}
+
+	creator, ok := writer.(Creator)
+	if ok {
+		return creator.Create(ctx, location, modTime)
+	}
+
+	w = NewPipeFileWriter(ctx, location, func(ctx context.Context, src io.Reader) (finalLocation []string, err error) {
+		return writer.WriteFile(ctx, location, src, modTime)
+	})
+	return w, nil
+}
+
+// Writes the contents of src through the writer returned by Create, aborting the file on failure.
+// Unlike WriteFile, backends implementing Creator stream the contents without buffering the whole file first
+func CreateFrom(ctx context.Context, writer Writer, location []string, src io.Reader, modTime time.Time) (finalLocation []string, err error) {
+	w, err := Create(ctx, writer, location, modTime)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = ioutils.CopyContext(ctx, w, src, ioutils.DefaultBufferSize)
+	if err != nil {
+		w.Abort()
+		return nil, fmt.Errorf("failed to copy contents: %w", err)
+	}
+
+	err = w.Close()
+	if err != nil {
+		return nil, err
+	}
+	return w.Location(), nil
+}
+
+type pipeFileWriter struct {
+	location []string
+	pipe     *io.PipeWriter
+	cancel   context.CancelFunc
+	done     chan error
+	once     sync.Once
+	aborted  bool
+	err      error
+}
+
+// Returns a FileWriter feeding the reader consumed by commit, which runs in its own goroutine.
+// Close waits for commit to return, Abort fails its pending and future reads
+// The location returned by commit is reported by Location.
+func NewPipeFileWriter(ctx context.Context, location []string, commit func(ctx context.Context, src io.Reader) (finalLocation []string, err error)) (w FileWriter) {
+	ctx, cancel := context.WithCancel(ctx)
+	reader, writer := io.Pipe()
+
+	pw := &pipeFileWriter{
+		location: location,
+		pipe:     writer,
+		cancel:   cancel,
+		done:     make(chan error, 1),
+	}
+	go func() {
+		finalLocation, err := commit(ctx, reader)
+		if err == nil && finalLocation != nil {
+			pw.location = finalLocation
+		}
+		// Writes after commit returned fail instead of blocking
+		reader.CloseWithError(err)
+		pw.done <- err
+	}()
+	return pw
+}
+
+var _ FileWriter = (*pipeFileWriter)(nil)
+
+func (w *pipeFileWriter) Write(b []byte) (n int, err error) {
+	return w.pipe.Write(b)
+}
+
+func (w *pipeFileWriter) finish(aborted bool) (err error) {
+	w.once.Do(func() {
+		w.aborted = aborted
+		// The context is only cancelled after commit returns, so it can still clean up
+		if aborted {
+			w.pipe.CloseWithError(errAborted)
+		} else {
+			w.pipe.Close()
+		}
+		w.err = <-w.done
+		w.cancel()
+	})
+	if w.aborted {
+		return nil
+	}
+	return w.err
+}
+
+func (w *pipeFileWriter) Close() (err error) {
+	err = w.finish(false)
+	if err != nil {
+		return fmt.Errorf("failed to commit file: %w", err)
+	}
+	return nil
+}
+
+func (w *pipeFileWriter) Abort() (err error) {
+	return w.finish(true)
+}
+
+func (w *pipeFileWriter) Location() (location []string) {
+	return w.location
+}
@@ -0,0 +1,336 @@
+// Copyright (C) 2025 ZedCloud Org.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package cryptfs
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"iter"
+	"time"
+
+	"github.com/pluto-org-co/fsio/filesystem"
+	"github.com/pluto-org-co/fsio/filesystem/utils"
+	"github.com/pluto-org-co/fsio/ioutils"
+)
+
+type CryptCtx struct {
+	// Key encrypting the location segments. Empty keeps the locations in plain text
+	NamesKeyID string
+}
+
+type CryptOption func(ctx *CryptCtx) (err error)
+
+// Encrypts the location segments with the key of the passed id.
+// Unlike the contents, names keep using this key after rotating the keyring, otherwise previous files
+// could no longer be found. Encrypted segments are a third longer than the original ones plus 38 bytes
+func WithCryptOptionNames(keyID string) (option CryptOption) {
+	return func(ctx *CryptCtx) (err error) {
+		ctx.NamesKeyID = keyID
+		return nil
+	}
+}
+
+// This FS encrypts the contents before writing them into the underlying Filesystem and decrypts them on read.
+// Contents are encrypted with the current key of the keyring and decrypted with the key found in their header.
+// Sizes and content types are only known after decryption
+type Crypt struct {
+	fs      filesystem.Filesystem
+	keyring *Keyring
+	// Nil when names are not encrypted
+	names *nameCipher
+}
+
+var (
+	_ filesystem.Filesystem           = (*Crypt)(nil)
+	_ filesystem.CapabilitiesReporter = (*Crypt)(nil)
+	_ filesystem.RangeReader          = (*Crypt)(nil)
+	_ filesystem.Creator              = (*Crypt)(nil)
+)
+
+func New(keyring *Keyring, fs filesystem.Filesystem, options ...CryptOption) (c *Crypt, err error) {
+	var cryptCtx CryptCtx
+	for _, option := range options {
+		err = option(&cryptCtx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to apply option: %w", err)
+		}
+	}
+
+	c = &Crypt{
+		fs:      fs,
+		keyring: keyring,
+	}
+	if cryptCtx.NamesKeyID != "" {
+		key, err := keyring.Get(cryptCtx.NamesKeyID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get names key: %w", err)
+		}
+
+		c.names, err = newNameCipher(key)
+		if err != nil {
+			return nil, fmt.Errorf("failed to prepare names cipher: %w", err)
+		}
+	}
+	return c, nil
+}
+
+// Location in the underlying filesystem
+func (c *Crypt) encryptLocation(location []string) (encrypted []string, err error) {
+	err = filesystem.ValidateLocation(location)
+	if err != nil {
+		return nil, err
+	}
+
+	if c.names == nil {
+		return location, nil
+	}
+	return c.names.encryptLocation(location), nil
+}
+
+func (c *Crypt) decryptLocation(encrypted []string) (location []string, err error) {
+	if c.names == nil {
+		return encrypted, nil
+	}
+	return c.names.decryptLocation(encrypted)
+}
+
+// Same capabilities of the underlying filesystem. Encrypted contents can't be read by ranges
+func (c *Crypt) Capabilities() (caps filesystem.Capability) {
+	return filesystem.Capabilities(c.fs) &^ filesystem.CapabilityRangeRead
+}
+
+func (c *Crypt) ChecksumTime(ctx context.Context, location []string) (checksum string, err error) {
+	encrypted, err := c.encryptLocation(location)
+	if err != nil {
+		return "", err
+	}
+	return c.fs.ChecksumTime(ctx, encrypted)
+}
+
+func (c *Crypt) ChecksumSha256(ctx context.Context, location []string) (checksum string, err error) {
+	file, err := c.Open(ctx, location)
+	if err != nil {
+		return "", fmt.Errorf("failed to open file: %w", err)
+	}
+	defer file.Close()
+
+	checksum, err = ioutils.ChecksumSha256(ctx, file)
+	if err != nil {
+		return "", fmt.Errorf("failed to compute hash: %w", err)
+	}
+	return checksum, nil
+}
+
+// Sizes, content types and tags of the underlying filesystem refer to the encrypted contents, so they are reported as unknown
+func (c *Crypt) Stat(ctx context.Context, location []string) (info *filesystem.FileInfo, err error) {
+	encrypted, err := c.encryptLocation(location)
+	if err != nil {
+		return nil, err
+	}
+
+	info, err = c.fs.Stat(ctx, encrypted)
+	if err != nil {
+		return nil, err
+	}
+
+	info.Location, err = c.decryptLocation(info.Location)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt location: %w", err)
+	}
+	info.Size = -1
+	info.ContentType = ""
+	info.ETag = ""
+	return info, nil
+}
+
+// Files with names that can't be decrypted are not listed
+func (c *Crypt) Files(ctx context.Context, options ...filesystem.ListOption) (seq iter.Seq2[filesystem.FileEntry, error]) {
+	listCtx := filesystem.NewListCtx(options...)
+	prefix := listCtx.Prefix
+	if c.names != nil {
+		prefix = c.names.encryptLocation(prefix)
+	}
+
+	return func(yield func(filesystem.FileEntry, error) bool) {
+		entries := c.fs.Files(ctx,
+			filesystem.WithListOptionPrefix(prefix...),
+			filesystem.WithListOptionRecursive(listCtx.Recursive),
+		)
+		for entry, err := range entries {
+			if err != nil {
+				yield(nil, err)
+				return
+			}
+
+			location, err := c.decryptLocation(entry.Location())
+			if err != nil {
+				continue
+			}
+
+			entry = &filesystem.SimpleFileEntry{
+				LocationValue: location,
+				ModTimeValue:  entry.ModTime(),
+				SizeValue:     -1,
+				SysValue:      entry.Sys(),
+			}
+			if !yield(entry, nil) {
+				return
+			}
+		}
+	}
+}
+
+func (c *Crypt) Open(ctx context.Context, location []string) (rc io.ReadCloser, err error) {
+	encrypted, err := c.encryptLocation(location)
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := c.fs.Open(ctx, encrypted)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open file: %w", err)
+	}
+
+	reader, err := newDecrypter(file, c.keyring)
+	if err != nil {
+		file.Close()
+		return nil, fmt.Errorf("failed to prepare decryption: %w", err)
+	}
+	return utils.NewSeparateReadCloser(file, reader), nil
+}
+
+// Offsets refer to the decrypted contents, so the underlying ranged reads can't be used.
+// The file is decrypted and the first offset bytes discarded
+func (c *Crypt) OpenRange(ctx context.Context, location []string, offset, length int64) (rc io.ReadCloser, err error) {
+	return filesystem.OpenRangeFallback(ctx, c, location, offset, length)
+}
+
+// Contents are encrypted while being written, they are never stored in plain text
+func (c *Crypt) WriteFile(ctx context.Context, location []string, src io.Reader, modTime time.Time) (finalLocation []string, err error) {
+	return filesystem.CreateFrom(ctx, c, location, src, modTime)
+}
+
+// Encrypts the written contents into a file created in the underlying filesystem.
+// Aborting the writer aborts the underlying file
+func (c *Crypt) Create(ctx context.Context, location []string, modTime time.Time) (w filesystem.FileWriter, err error) {
+	encrypted, err := c.encryptLocation(location)
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := filesystem.Create(ctx, c.fs, encrypted, modTime)
+	if err != nil {
+		return nil, err
+	}
+
+	encrypter, err := newEncrypter(file, c.keyring.Current())
+	if err != nil {
+		file.Abort()
+		return nil, fmt.Errorf("failed to prepare encryption: %w", err)
+	}
+
+	w = &cryptWriter{
+		crypt:     c,
+		file:      file,
+		encrypter: encrypter,
+		location:  location,
+	}
+	return w, nil
+}
+
+type cryptWriter struct {
+	crypt     *Crypt
+	file      filesystem.FileWriter
+	encrypter *encrypter
+	location  []string
+	done      bool
+}
+
+var _ filesystem.FileWriter = (*cryptWriter)(nil)
+
+func (w *cryptWriter) Write(b []byte) (n int, err error) {
+	return w.encrypter.Write(b)
+}
+
+// Seals the last chunk before committing the underlying file
+func (w *cryptWriter) Close() (err error) {
+	if w.done {
+		return nil
+	}
+	w.done = true
+
+	err = w.encrypter.Close()
+	if err != nil {
+		w.file.Abort()
+		return fmt.Errorf("failed to encrypt contents: %w", err)
+	}
+
+	err = w.file.Close()
+	if err != nil {
+		return err
+	}
+
+	location, err := w.crypt.decryptLocation(w.file.Location())
+	if err != nil {
+		return fmt.Errorf("failed to decrypt location: %w", err)
+	}
+	w.location = location
+	return nil
+}
+
+func (w *cryptWriter) Abort() (err error) {
+	if w.done {
+		return nil
+	}
+	w.done = true
+	return w.file.Abort()
+}
+
+func (w *cryptWriter) Location() (location []string) {
+	return w.location
+}
+
+func (c *Crypt) RemoveAll(ctx context.Context, location []string) (err error) {
+	encrypted, err := c.encryptLocation(location)
+	if err != nil {
+		return err
+	}
+	return c.fs.RemoveAll(ctx, encrypted)
+}
+
+func (c *Crypt) Move(ctx context.Context, oldLocation, newLocation []string) (finalLocation []string, err error) {
+	encryptedOld, err := c.encryptLocation(oldLocation)
+	if err != nil {
+		return nil, err
+	}
+
+	encryptedNew, err := c.encryptLocation(newLocation)
+	if err != nil {
+		return nil, err
+	}
+
+	finalLocation, err = c.fs.Move(ctx, encryptedOld, encryptedNew)
+	if err != nil {
+		return nil, err
+	}
+
+	finalLocation, err = c.decryptLocation(finalLocation)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt location: %w", err)
+	}
+	return finalLocation, nil
+}
@@ -0,0 +1,340 @@
+// Copyright (C) 2025 ZedCloud Org.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package cryptfs_test
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/pluto-org-co/fsio/filesystem"
+	"github.com/pluto-org-co/fsio/filesystem/cryptfs"
+	"github.com/pluto-org-co/fsio/filesystem/directory"
+	"github.com/pluto-org-co/fsio/filesystem/testsuite"
+	"github.com/stretchr/testify/assert"
+)
+
+func readAll(ctx context.Context, fs filesystem.Filesystem, location []string) (contents []byte, err error) {
+	file, err := fs.Open(ctx, location)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return io.ReadAll(file)
+}
+
+func Test_Crypt(t *testing.T) {
+	assertions := assert.New(t)
+
+	key, err := cryptfs.GenerateKey("first")
+	if !assertions.Nil(err, "failed to generate key") {
+		return
+	}
+	keyring, err := cryptfs.NewKeyring(key)
+	if !assertions.Nil(err, "failed to prepare keyring") {
+		return
+	}
+
+	type Test struct {
+		Name    string
+		Options []cryptfs.CryptOption
+	}
+	tests := []Test{
+		{Name: "PlainNames"},
+		{Name: "EncryptedNames", Options: []cryptfs.CryptOption{cryptfs.WithCryptOptionNames(key.ID)}},
+	}
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			assertions := assert.New(t)
+
+			tempDir, err := os.MkdirTemp("", "*")
+			if !assertions.Nil(err, "failed to create temp") {
+				return
+			}
+			defer os.RemoveAll(tempDir)
+			localRoot := directory.New(tempDir, 0o777, 0o777)
+
+			cryptRoot, err := cryptfs.New(keyring, localRoot, test.Options...)
+			if !assertions.Nil(err, "failed to prepare crypt fs") {
+				return
+			}
+
+			t.Run("Testsuite", testsuite.TestFilesystem(t, cryptRoot))
+		})
+	}
+}
+
+func Test_Crypt_Rotation(t *testing.T) {
+	assertions := assert.New(t)
+
+	ctx, cancel := context.WithTimeout(context.TODO(), time.Minute)
+	defer cancel()
+
+	oldKey, err := cryptfs.GenerateKey("old")
+	if !assertions.Nil(err, "failed to generate key") {
+		return
+	}
+	newKey, err := cryptfs.GenerateKey("new")
+	if !assertions.Nil(err, "failed to generate key") {
+		return
+	}
+
+	tempDir := t.TempDir()
+	localRoot := directory.New(tempDir, 0o777, 0o777)
+
+	oldKeyring, err := cryptfs.NewKeyring(oldKey)
+	if !assertions.Nil(err, "failed to prepare keyring") {
+		return
+	}
+	oldRoot, err := cryptfs.New(oldKeyring, localRoot, cryptfs.WithCryptOptionNames(oldKey.ID))
+	if !assertions.Nil(err, "failed to prepare crypt fs") {
+		return
+	}
+
+	// Names keep using the previous key after the rotation
+	rotatedKeyring, err := cryptfs.NewKeyring(newKey, oldKey)
+	if !assertions.Nil(err, "failed to prepare keyring") {
+		return
+	}
+	rotatedRoot, err := cryptfs.New(rotatedKeyring, localRoot, cryptfs.WithCryptOptionNames(oldKey.ID))
+	if !assertions.Nil(err, "failed to prepare crypt fs") {
+		return
+	}
+
+	type Test struct {
+		Name string
+		Size int
+	}
+	tests := []Test{
+		{Name: "Empty", Size: 0},
+		{Name: "Small", Size: 100},
+		{Name: "ChunkBoundary", Size: 128 * 1024},
+		{Name: "Chunks", Size: 200*1024 + 7},
+	}
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			assertions := assert.New(t)
+
+			contents := make([]byte, test.Size)
+			rand.Read(contents)
+
+			oldLocation := []string{"old", test.Name + ".bin"}
+			_, err := oldRoot.WriteFile(ctx, oldLocation, bytes.NewReader(contents), time.Now())
+			if !assertions.Nil(err, "failed to write file") {
+				return
+			}
+			newLocation := []string{"new", test.Name + ".bin"}
+			_, err = rotatedRoot.WriteFile(ctx, newLocation, bytes.NewReader(contents), time.Now())
+			if !assertions.Nil(err, "failed to write file") {
+				return
+			}
+
+			for _, location := range [][]string{oldLocation, newLocation} {
+				decrypted, err := readAll(ctx, rotatedRoot, location)
+				if !assertions.Nil(err, "failed to read file: %v", location) {
+					return
+				}
+				assertions.Equal(contents, decrypted, "decrypted contents doesn't match: %v", location)
+			}
+
+			_, err = oldRoot.Stat(ctx, newLocation)
+			if !assertions.Nil(err, "files should be found with the same names key") {
+				return
+			}
+			_, err = readAll(ctx, oldRoot, newLocation)
+			assertions.ErrorIs(err, cryptfs.ErrUnknownKey, "files encrypted with a newer key should not be readable")
+		})
+	}
+
+	t.Run("EncryptedNames", func(t *testing.T) {
+		assertions := assert.New(t)
+
+		err := filepath.WalkDir(tempDir, func(path string, entry os.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			relative, _ := filepath.Rel(tempDir, path)
+			assertions.NotContains(relative, ".bin", "names should be encrypted")
+			return nil
+		})
+		assertions.Nil(err, "failed to walk directory")
+
+		var count int
+		for entry, err := range rotatedRoot.Files(ctx, filesystem.WithListOptionPrefix("old")) {
+			if !assertions.Nil(err, "failed to list files") {
+				return
+			}
+			assertions.Equal("old", entry.Location()[0], "unexpected listed location")
+			count++
+		}
+		assertions.Equal(len(tests), count, "unexpected listed files")
+	})
+}
+
+func Test_Crypt_Tampered(t *testing.T) {
+	assertions := assert.New(t)
+
+	ctx, cancel := context.WithTimeout(context.TODO(), time.Minute)
+	defer cancel()
+
+	key, err := cryptfs.GenerateKey("key")
+	if !assertions.Nil(err, "failed to generate key") {
+		return
+	}
+	keyring, err := cryptfs.NewKeyring(key)
+	if !assertions.Nil(err, "failed to prepare keyring") {
+		return
+	}
+
+	tempDir := t.TempDir()
+	cryptRoot, err := cryptfs.New(keyring, directory.New(tempDir, 0o777, 0o777))
+	if !assertions.Nil(err, "failed to prepare crypt fs") {
+		return
+	}
+
+	// Magic, key id length, key id and salt
+	headerSize := 8 + 1 + len(key.ID) + 32
+	// Sealed chunk, including the authentication tag
+	chunkSize := 64*1024 + 16
+
+	type Test struct {
+		Name   string
+		Modify func(contents []byte) (modified []byte)
+	}
+	tests := []Test{
+		{
+			Name: "FlippedByte",
+			Modify: func(contents []byte) (modified []byte) {
+				contents[len(contents)/2] ^= 1
+				return contents
+			},
+		},
+		{
+			Name: "TruncatedChunk",
+			Modify: func(contents []byte) (modified []byte) {
+				return contents[:len(contents)-1]
+			},
+		},
+		{
+			Name: "DroppedChunks",
+			Modify: func(contents []byte) (modified []byte) {
+				return contents[:headerSize+chunkSize]
+			},
+		},
+		{
+			Name: "SwappedChunks",
+			Modify: func(contents []byte) (modified []byte) {
+				first := bytes.Clone(contents[headerSize : headerSize+chunkSize])
+				copy(contents[headerSize:], contents[headerSize+chunkSize:headerSize+2*chunkSize])
+				copy(contents[headerSize+chunkSize:], first)
+				return contents
+			},
+		},
+		{
+			Name: "Plain",
+			Modify: func(contents []byte) (modified []byte) {
+				return []byte("not encrypted")
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			assertions := assert.New(t)
+
+			location := []string{test.Name + ".bin"}
+			_, err := cryptRoot.WriteFile(ctx, location, bytes.NewReader(make([]byte, 200*1024)), time.Now())
+			if !assertions.Nil(err, "failed to write file") {
+				return
+			}
+
+			filename := filepath.Join(tempDir, location[0])
+			contents, err := os.ReadFile(filename)
+			if !assertions.Nil(err, "failed to read encrypted file") {
+				return
+			}
+			err = os.WriteFile(filename, test.Modify(contents), 0o666)
+			if !assertions.Nil(err, "failed to write tampered file") {
+				return
+			}
+
+			_, err = readAll(ctx, cryptRoot, location)
+			assertions.ErrorIs(err, cryptfs.ErrCorrupted, "tampered contents should fail to decrypt")
+		})
+	}
+}
+
+func Test_ParseKeys(t *testing.T) {
+	assertions := assert.New(t)
+
+	first, err := cryptfs.GenerateKey("first")
+	if !assertions.Nil(err, "failed to generate key") {
+		return
+	}
+	second, err := cryptfs.GenerateKey("second")
+	if !assertions.Nil(err, "failed to generate key") {
+		return
+	}
+
+	type Test struct {
+		Name     string
+		Text     string
+		Expected []cryptfs.Key
+		Fails    bool
+	}
+	tests := []Test{
+		{Name: "Lines", Text: "# current key\n" + first.String() + "\n\n" + second.String() + "\n", Expected: []cryptfs.Key{first, second}},
+		{Name: "Commas", Text: first.String() + "," + second.String(), Expected: []cryptfs.Key{first, second}},
+		{Name: "MissingID", Text: "c2VjcmV0", Fails: true},
+		{Name: "InvalidBase64", Text: "id:???", Fails: true},
+	}
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			assertions := assert.New(t)
+
+			keys, err := cryptfs.ParseKeys(test.Text)
+			if test.Fails {
+				assertions.ErrorIs(err, cryptfs.ErrInvalidKey, "expecting invalid key")
+				return
+			}
+			if !assertions.Nil(err, "failed to parse keys") {
+				return
+			}
+			assertions.Equal(test.Expected, keys, "unexpected keys")
+
+			t.Setenv("FSIO_TEST_KEYS", test.Text)
+			keyring, err := cryptfs.LoadKeyEnv("FSIO_TEST_KEYS")
+			if !assertions.Nil(err, "failed to load keys") {
+				return
+			}
+			assertions.Equal(first.ID, keyring.Current().ID, "the first key should be the current one")
+		})
+	}
+
+	t.Run("InvalidSize", func(t *testing.T) {
+		assertions := assert.New(t)
+
+		_, err := cryptfs.NewKeyring(cryptfs.Key{ID: "short", Secret: []byte("short")})
+		assertions.ErrorIs(err, cryptfs.ErrInvalidKey, "expecting invalid key size")
+
+		_, err = cryptfs.NewKeyring(first, first)
+		assertions.ErrorIs(err, cryptfs.ErrInvalidKey, "expecting duplicated id")
+	})
+}
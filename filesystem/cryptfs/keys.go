@@ -0,0 +1,168 @@
+// Copyright (C) 2025 ZedCloud Org.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package cryptfs
+
+import (
+	"bufio"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Size in bytes of the AES-256 master keys
+const KeySize = 32
+
+var (
+	// Returned when a key can't be parsed or has the wrong size
+	ErrInvalidKey = errors.New("invalid key")
+	// Returned when the contents were encrypted with a key missing from the keyring
+	ErrUnknownKey = errors.New("unknown key")
+)
+
+// Master key identified by an ID stored in the header of every file it encrypted
+type Key struct {
+	ID     string
+	Secret []byte
+}
+
+// Creates a random key
+func GenerateKey(id string) (key Key, err error) {
+	key = Key{ID: id, Secret: make([]byte, KeySize)}
+	_, err = rand.Read(key.Secret)
+	if err != nil {
+		return key, fmt.Errorf("failed to generate secret: %w", err)
+	}
+	return key, nil
+}
+
+// Encodes the key as id:base64-secret, the format read by ParseKeys
+func (k Key) String() (s string) {
+	return k.ID + ":" + base64.StdEncoding.EncodeToString(k.Secret)
+}
+
+func (k Key) validate() (err error) {
+	switch {
+	case k.ID == "":
+		return fmt.Errorf("%w: empty id", ErrInvalidKey)
+	case len(k.ID) > 255:
+		return fmt.Errorf("%w: id longer than 255 bytes: %q", ErrInvalidKey, k.ID)
+	case len(k.Secret) != KeySize:
+		return fmt.Errorf("%w: %s: expecting %d bytes secret, got %d", ErrInvalidKey, k.ID, KeySize, len(k.Secret))
+	default:
+		return nil
+	}
+}
+
+// Parses keys encoded as id:base64-secret, separated by new lines, spaces or commas.
+// Empty lines and lines starting with # are ignored
+func ParseKeys(text string) (keys []Key, err error) {
+	scanner := bufio.NewScanner(strings.NewReader(text))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		for _, field := range strings.FieldsFunc(line, func(r rune) bool { return r == ',' || r == ' ' || r == '\t' }) {
+			id, encoded, found := strings.Cut(field, ":")
+			if !found {
+				return nil, fmt.Errorf("%w: missing id separator", ErrInvalidKey)
+			}
+
+			secret, err := base64.StdEncoding.DecodeString(encoded)
+			if err != nil {
+				return nil, fmt.Errorf("%w: %s: failed to decode secret: %w", ErrInvalidKey, id, err)
+			}
+			keys = append(keys, Key{ID: id, Secret: secret})
+		}
+	}
+	return keys, scanner.Err()
+}
+
+// Keys available for decryption. The first one encrypts the new files,
+// so keys are rotated by prepending a new one while keeping the previous ones
+type Keyring struct {
+	current Key
+	keys    map[string]Key
+}
+
+func NewKeyring(keys ...Key) (keyring *Keyring, err error) {
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("%w: no keys provided", ErrInvalidKey)
+	}
+
+	keyring = &Keyring{
+		current: keys[0],
+		keys:    make(map[string]Key, len(keys)),
+	}
+	for _, key := range keys {
+		err = key.validate()
+		if err != nil {
+			return nil, err
+		}
+
+		_, found := keyring.keys[key.ID]
+		if found {
+			return nil, fmt.Errorf("%w: duplicated id: %s", ErrInvalidKey, key.ID)
+		}
+		keyring.keys[key.ID] = key
+	}
+	return keyring, nil
+}
+
+// Reads the keys from a file in the ParseKeys format
+func LoadKeyFile(filename string) (keyring *Keyring, err error) {
+	contents, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read key file: %w", err)
+	}
+
+	keys, err := ParseKeys(string(contents))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse key file: %w", err)
+	}
+	return NewKeyring(keys...)
+}
+
+// Reads the keys from an environment variable in the ParseKeys format
+func LoadKeyEnv(name string) (keyring *Keyring, err error) {
+	value, found := os.LookupEnv(name)
+	if !found {
+		return nil, fmt.Errorf("%w: environment variable not set: %s", ErrInvalidKey, name)
+	}
+
+	keys, err := ParseKeys(value)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse environment variable: %s: %w", name, err)
+	}
+	return NewKeyring(keys...)
+}
+
+// Key used for encrypting new files
+func (k *Keyring) Current() (key Key) {
+	return k.current
+}
+
+func (k *Keyring) Get(id string) (key Key, err error) {
+	key, found := k.keys[id]
+	if !found {
+		return key, fmt.Errorf("%w: %s", ErrUnknownKey, id)
+	}
+	return key, nil
+}
@@ -0,0 +1,99 @@
+// Copyright (C) 2025 ZedCloud Org.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package cryptfs
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hkdf"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+)
+
+// Encrypts location segments deterministically, so encrypted locations can be looked up and listed by prefix.
+// The nonce is the HMAC of the segment, equal segments share the same encrypted name
+type nameCipher struct {
+	aead   cipher.AEAD
+	macKey []byte
+}
+
+func newNameCipher(key Key) (n *nameCipher, err error) {
+	derived, err := hkdf.Key(sha256.New, key.Secret, nil, "fsio cryptfs names", 2*KeySize)
+	if err != nil {
+		return nil, fmt.Errorf("failed to derive key: %w", err)
+	}
+
+	block, err := aes.NewCipher(derived[:KeySize])
+	if err != nil {
+		return nil, fmt.Errorf("failed to prepare cipher: %w", err)
+	}
+
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("failed to prepare gcm: %w", err)
+	}
+
+	n = &nameCipher{
+		aead:   aead,
+		macKey: derived[KeySize:],
+	}
+	return n, nil
+}
+
+func (n *nameCipher) encrypt(segment string) (encrypted string) {
+	mac := hmac.New(sha256.New, n.macKey)
+	mac.Write([]byte(segment))
+	nonce := mac.Sum(nil)[:n.aead.NonceSize()]
+
+	sealed := n.aead.Seal(nonce, nonce, []byte(segment), nil)
+	return base64.RawURLEncoding.EncodeToString(sealed)
+}
+
+func (n *nameCipher) decrypt(encrypted string) (segment string, err error) {
+	sealed, err := base64.RawURLEncoding.DecodeString(encrypted)
+	if err != nil || len(sealed) < n.aead.NonceSize()+n.aead.Overhead() {
+		return "", fmt.Errorf("%w: invalid name: %s", ErrCorrupted, encrypted)
+	}
+
+	nonce := sealed[:n.aead.NonceSize()]
+	plain, err := n.aead.Open(nil, nonce, sealed[n.aead.NonceSize():], nil)
+	if err != nil {
+		return "", fmt.Errorf("%w: invalid name: %s", ErrCorrupted, encrypted)
+	}
+	return string(plain), nil
+}
+
+func (n *nameCipher) encryptLocation(location []string) (encrypted []string) {
+	encrypted = make([]string, 0, len(location))
+	for _, segment := range location {
+		encrypted = append(encrypted, n.encrypt(segment))
+	}
+	return encrypted
+}
+
+func (n *nameCipher) decryptLocation(encrypted []string) (location []string, err error) {
+	location = make([]string, 0, len(encrypted))
+	for _, segment := range encrypted {
+		plain, err := n.decrypt(segment)
+		if err != nil {
+			return nil, err
+		}
+		location = append(location, plain)
+	}
+	return location, nil
+}
@@ -0,0 +1,238 @@
+// Copyright (C) 2025 ZedCloud Org.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package cryptfs
+
+import (
+	"bufio"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hkdf"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+)
+
+// Encrypted contents layout:
+//
+//	magic | key id length (1 byte) | key id | salt | chunk...
+//
+// Each file is encrypted with its own AES-256-GCM key derived from the master key and the random salt.
+// Contents are split in chunks sealed independently, their nonce holds the chunk counter and a flag set
+// only for the last chunk, so reordered, truncated or extended contents fail to decrypt
+const (
+	magic     = "FSIOENC1"
+	saltSize  = 32
+	chunkSize = 64 * 1024
+)
+
+// Returned when the contents can't be authenticated
+var ErrCorrupted = errors.New("corrupted or tampered contents")
+
+func contentsCipher(key Key, salt []byte) (aead cipher.AEAD, err error) {
+	derived, err := hkdf.Key(sha256.New, key.Secret, salt, "fsio cryptfs contents", KeySize)
+	if err != nil {
+		return nil, fmt.Errorf("failed to derive key: %w", err)
+	}
+
+	block, err := aes.NewCipher(derived)
+	if err != nil {
+		return nil, fmt.Errorf("failed to prepare cipher: %w", err)
+	}
+	return cipher.NewGCM(block)
+}
+
+func chunkNonce(nonce []byte, counter uint32, last bool) {
+	clear(nonce)
+	binary.BigEndian.PutUint32(nonce[len(nonce)-5:], counter)
+	if last {
+		nonce[len(nonce)-1] = 1
+	}
+}
+
+type encrypter struct {
+	dst     io.Writer
+	aead    cipher.AEAD
+	nonce   []byte
+	buffer  []byte
+	counter uint32
+}
+
+// Writes the header into dst. The last chunk is only written on Close
+func newEncrypter(dst io.Writer, key Key) (e *encrypter, err error) {
+	salt := make([]byte, saltSize)
+	_, err = rand.Read(salt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate salt: %w", err)
+	}
+
+	aead, err := contentsCipher(key, salt)
+	if err != nil {
+		return nil, err
+	}
+
+	header := make([]byte, 0, len(magic)+1+len(key.ID)+saltSize)
+	header = append(header, magic...)
+	header = append(header, byte(len(key.ID)))
+	header = append(header, key.ID...)
+	header = append(header, salt...)
+	_, err = dst.Write(header)
+	if err != nil {
+		return nil, fmt.Errorf("failed to write header: %w", err)
+	}
+
+	e = &encrypter{
+		dst:    dst,
+		aead:   aead,
+		nonce:  make([]byte, aead.NonceSize()),
+		buffer: make([]byte, 0, chunkSize+aead.Overhead()),
+	}
+	return e, nil
+}
+
+func (e *encrypter) seal(last bool) (err error) {
+	chunkNonce(e.nonce, e.counter, last)
+	sealed := e.aead.Seal(e.buffer[:0], e.nonce, e.buffer, nil)
+	_, err = e.dst.Write(sealed)
+	if err != nil {
+		return fmt.Errorf("failed to write chunk: %w", err)
+	}
+
+	if e.counter == math.MaxUint32 {
+		return errors.New("contents too large")
+	}
+	e.counter++
+	e.buffer = e.buffer[:0]
+	return nil
+}
+
+// A full chunk is only sealed once more contents arrive, so the last chunk is never empty unless the contents are
+func (e *encrypter) Write(p []byte) (n int, err error) {
+	for len(p) > 0 {
+		if len(e.buffer) == chunkSize {
+			err = e.seal(false)
+			if err != nil {
+				return n, err
+			}
+		}
+
+		copied := copy(e.buffer[len(e.buffer):chunkSize], p)
+		e.buffer = e.buffer[:len(e.buffer)+copied]
+		p = p[copied:]
+		n += copied
+	}
+	return n, nil
+}
+
+// Seals the last chunk. The underlying writer is not closed
+func (e *encrypter) Close() (err error) {
+	return e.seal(true)
+}
+
+type decrypter struct {
+	src     *bufio.Reader
+	aead    cipher.AEAD
+	nonce   []byte
+	buffer  []byte
+	plain   []byte
+	counter uint32
+	done    bool
+}
+
+// Reads the header from src and selects the key from the keyring
+func newDecrypter(src io.Reader, keyring *Keyring) (d *decrypter, err error) {
+	reader := bufio.NewReaderSize(src, chunkSize)
+
+	header := make([]byte, len(magic)+1)
+	_, err = io.ReadFull(reader, header)
+	if err != nil || string(header[:len(magic)]) != magic {
+		return nil, fmt.Errorf("%w: missing header", ErrCorrupted)
+	}
+
+	idAndSalt := make([]byte, int(header[len(magic)])+saltSize)
+	_, err = io.ReadFull(reader, idAndSalt)
+	if err != nil {
+		return nil, fmt.Errorf("%w: truncated header", ErrCorrupted)
+	}
+
+	key, err := keyring.Get(string(idAndSalt[:len(idAndSalt)-saltSize]))
+	if err != nil {
+		return nil, err
+	}
+
+	aead, err := contentsCipher(key, idAndSalt[len(idAndSalt)-saltSize:])
+	if err != nil {
+		return nil, err
+	}
+
+	d = &decrypter{
+		src:    reader,
+		aead:   aead,
+		nonce:  make([]byte, aead.NonceSize()),
+		buffer: make([]byte, chunkSize+aead.Overhead()),
+	}
+	return d, nil
+}
+
+func (d *decrypter) next() (err error) {
+	n, err := io.ReadFull(d.src, d.buffer)
+	var last bool
+	switch {
+	case errors.Is(err, io.EOF):
+		return fmt.Errorf("%w: missing last chunk", ErrCorrupted)
+	case errors.Is(err, io.ErrUnexpectedEOF):
+		last = true
+	case err != nil:
+		return fmt.Errorf("failed to read chunk: %w", err)
+	default:
+		_, err = d.src.Peek(1)
+		switch {
+		case errors.Is(err, io.EOF):
+			last = true
+		case err != nil:
+			return fmt.Errorf("failed to read chunk: %w", err)
+		}
+	}
+
+	chunkNonce(d.nonce, d.counter, last)
+	d.plain, err = d.aead.Open(d.buffer[:0], d.nonce, d.buffer[:n], nil)
+	if err != nil {
+		return fmt.Errorf("%w: chunk %d", ErrCorrupted, d.counter)
+	}
+	d.counter++
+	d.done = last
+	return nil
+}
+
+func (d *decrypter) Read(p []byte) (n int, err error) {
+	for len(d.plain) == 0 {
+		if d.done {
+			return 0, io.EOF
+		}
+
+		err = d.next()
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	n = copy(p, d.plain)
+	d.plain = d.plain[n:]
+	return n, nil
+}
@@ -0,0 +1,327 @@
+// Copyright (C) 2025 ZedCloud Org.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package dedupfs
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"iter"
+	"os"
+	"time"
+
+	"github.com/pluto-org-co/fsio/filesystem"
+	"github.com/pluto-org-co/fsio/filesystem/subfs"
+	"github.com/pluto-org-co/fsio/ioutils"
+)
+
+const (
+	// Prefix of the location to blob index in the underlying filesystem
+	IndexRoot = "index"
+	// Prefix of the contents in the underlying filesystem, stored by the sha256 of their raw bytes
+	BlobsRoot = "blobs"
+)
+
+// This FS stores each unique content once in the underlying Filesystem.
+// Contents are stored as blobs named after the sha256 of their bytes and every file is a small
+// index entry pointing to its blob, written with the modification time of the file.
+// Removed and overwritten files leave their blobs behind until GarbageCollect runs
+type Dedup struct {
+	fs    filesystem.Filesystem
+	index *subfs.Sub
+	blobs *subfs.Sub
+}
+
+var (
+	_ filesystem.Filesystem           = (*Dedup)(nil)
+	_ filesystem.CapabilitiesReporter = (*Dedup)(nil)
+	_ filesystem.RangeReader          = (*Dedup)(nil)
+)
+
+func New(fs filesystem.Filesystem) (d *Dedup) {
+	return &Dedup{
+		fs:    fs,
+		index: subfs.New(fs, IndexRoot),
+		blobs: subfs.New(fs, BlobsRoot),
+	}
+}
+
+// Contents of an index entry
+type indexEntry struct {
+	// Sha256 of the raw contents, naming the blob
+	Blob string `json:"blob"`
+	// Checksum reported by ChecksumSha256. Office documents are checksummed by their normalized contents
+	Sha256 string `json:"sha256"`
+	Size   int64  `json:"size"`
+}
+
+func blobLocation(checksum string) (location []string) {
+	return []string{checksum[:2], checksum}
+}
+
+func (d *Dedup) Capabilities() (caps filesystem.Capability) {
+	return filesystem.Capabilities(d.fs)
+}
+
+func (d *Dedup) readEntry(ctx context.Context, location []string) (entry *indexEntry, err error) {
+	err = filesystem.ValidateLocation(location)
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := d.index.Open(ctx, location)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open index entry: %w", err)
+	}
+	defer file.Close()
+
+	entry = &indexEntry{}
+	err = json.NewDecoder(file).Decode(entry)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode index entry: %w", err)
+	}
+	if len(entry.Blob) < 2 {
+		return nil, fmt.Errorf("invalid index entry blob: %q", entry.Blob)
+	}
+	return entry, nil
+}
+
+func (d *Dedup) ChecksumTime(ctx context.Context, location []string) (checksum string, err error) {
+	return d.index.ChecksumTime(ctx, location)
+}
+
+// The checksum is stored in the index entry, so the contents are never read
+func (d *Dedup) ChecksumSha256(ctx context.Context, location []string) (checksum string, err error) {
+	entry, err := d.readEntry(ctx, location)
+	if err != nil {
+		return "", err
+	}
+	return entry.Sha256, nil
+}
+
+func (d *Dedup) Stat(ctx context.Context, location []string) (info *filesystem.FileInfo, err error) {
+	err = filesystem.ValidateLocation(location)
+	if err != nil {
+		return nil, err
+	}
+
+	info, err = d.index.Stat(ctx, location)
+	if err != nil {
+		return nil, err
+	}
+
+	entry, err := d.readEntry(ctx, location)
+	if err != nil {
+		return nil, err
+	}
+
+	blobInfo, err := d.blobs.Stat(ctx, blobLocation(entry.Blob))
+	if err != nil {
+		return nil, fmt.Errorf("failed to stat blob: %w", err)
+	}
+
+	info.Size = entry.Size
+	info.ContentType = blobInfo.ContentType
+	info.ETag = blobInfo.ETag
+	return info, nil
+}
+
+// Sizes and content types are stored in the index entries, so they are unknown while listing
+func (d *Dedup) Files(ctx context.Context, options ...filesystem.ListOption) (seq iter.Seq2[filesystem.FileEntry, error]) {
+	return func(yield func(filesystem.FileEntry, error) bool) {
+		for entry, err := range d.index.Files(ctx, options...) {
+			if err != nil {
+				yield(nil, err)
+				return
+			}
+
+			entry = &filesystem.SimpleFileEntry{
+				LocationValue: entry.Location(),
+				ModTimeValue:  entry.ModTime(),
+				SizeValue:     -1,
+				SysValue:      entry.Sys(),
+			}
+			if !yield(entry, nil) {
+				return
+			}
+		}
+	}
+}
+
+func (d *Dedup) Open(ctx context.Context, location []string) (rc io.ReadCloser, err error) {
+	entry, err := d.readEntry(ctx, location)
+	if err != nil {
+		return nil, err
+	}
+
+	rc, err = d.blobs.Open(ctx, blobLocation(entry.Blob))
+	if err != nil {
+		return nil, fmt.Errorf("failed to open blob: %w", err)
+	}
+	return rc, nil
+}
+
+func (d *Dedup) OpenRange(ctx context.Context, location []string, offset, length int64) (rc io.ReadCloser, err error) {
+	entry, err := d.readEntry(ctx, location)
+	if err != nil {
+		return nil, err
+	}
+
+	rc, err = d.blobs.OpenRange(ctx, blobLocation(entry.Blob), offset, length)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open blob: %w", err)
+	}
+	return rc, nil
+}
+
+// Contents are buffered into a temporary file to compute their checksums.
+// The blob is only uploaded when no other file stored the same contents before
+func (d *Dedup) WriteFile(ctx context.Context, location []string, src io.Reader, modTime time.Time) (finalLocation []string, err error) {
+	err = filesystem.ValidateLocation(location)
+	if err != nil {
+		return nil, err
+	}
+
+	temp, err := os.CreateTemp("", "*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create temporary file: %w", err)
+	}
+	defer temp.Close()
+	defer os.Remove(temp.Name())
+
+	switch src.(type) {
+	case *bufio.Reader:
+		break
+	default:
+		src = bufio.NewReaderSize(src, ioutils.DefaultBufferSize)
+	}
+
+	hash := sha256.New()
+	size, err := ioutils.CopyContext(ctx, io.MultiWriter(temp, hash), src, ioutils.DefaultBufferSize)
+	if err != nil {
+		return nil, fmt.Errorf("failed to copy contents: %w", err)
+	}
+	blob := hex.EncodeToString(hash.Sum(nil))
+
+	_, err = temp.Seek(0, io.SeekStart)
+	if err != nil {
+		return nil, fmt.Errorf("failed to rewind temporary file: %w", err)
+	}
+
+	checksum, err := ioutils.ChecksumSha256(ctx, temp)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compute checksum: %w", err)
+	}
+
+	_, err = d.blobs.Stat(ctx, blobLocation(blob))
+	switch {
+	case err == nil:
+		break
+	case errors.Is(err, fs.ErrNotExist):
+		_, err = temp.Seek(0, io.SeekStart)
+		if err != nil {
+			return nil, fmt.Errorf("failed to rewind temporary file: %w", err)
+		}
+
+		_, err = filesystem.CreateFrom(ctx, d.blobs, blobLocation(blob), temp, time.Now())
+		if err != nil {
+			return nil, fmt.Errorf("failed to write blob: %w", err)
+		}
+	default:
+		return nil, fmt.Errorf("failed to stat blob: %w", err)
+	}
+
+	contents, err := json.Marshal(&indexEntry{Blob: blob, Sha256: checksum, Size: size})
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode index entry: %w", err)
+	}
+
+	finalLocation, err = d.index.WriteFile(ctx, location, bytes.NewReader(contents), modTime)
+	if err != nil {
+		return nil, fmt.Errorf("failed to write index entry: %w", err)
+	}
+	return finalLocation, nil
+}
+
+// Only the index entries are removed, blobs are left for GarbageCollect
+func (d *Dedup) RemoveAll(ctx context.Context, location []string) (err error) {
+	err = filesystem.ValidateLocation(location)
+	if err != nil {
+		return err
+	}
+	return d.index.RemoveAll(ctx, location)
+}
+
+// Only the index entry is moved, the contents are never transferred
+func (d *Dedup) Move(ctx context.Context, oldLocation, newLocation []string) (finalLocation []string, err error) {
+	err = filesystem.ValidateLocation(oldLocation)
+	if err != nil {
+		return nil, err
+	}
+	err = filesystem.ValidateLocation(newLocation)
+	if err != nil {
+		return nil, err
+	}
+	return d.index.Move(ctx, oldLocation, newLocation)
+}
+
+// Removes the blobs no index entry refers to, returning the amount of removed blobs.
+// Nothing is removed when the index can't be read entirely.
+// Must not run while files are being written, a blob written concurrently may be removed before its
+// index entry exists
+func (d *Dedup) GarbageCollect(ctx context.Context) (removed int64, err error) {
+	referenced := map[string]struct{}{}
+	for entry, err := range d.index.Files(ctx) {
+		if err != nil {
+			return 0, fmt.Errorf("failed to list index: %w", err)
+		}
+
+		indexed, err := d.readEntry(ctx, entry.Location())
+		if err != nil {
+			return 0, fmt.Errorf("failed to read index entry: %v: %w", entry.Location(), err)
+		}
+		referenced[indexed.Blob] = struct{}{}
+	}
+
+	var unreferenced [][]string
+	for entry, err := range d.blobs.Files(ctx) {
+		if err != nil {
+			return 0, fmt.Errorf("failed to list blobs: %w", err)
+		}
+
+		location := entry.Location()
+		_, found := referenced[location[len(location)-1]]
+		if !found {
+			unreferenced = append(unreferenced, location)
+		}
+	}
+
+	for _, location := range unreferenced {
+		err = d.blobs.RemoveAll(ctx, location)
+		if err != nil {
+			return removed, fmt.Errorf("failed to remove blob: %s: %w", location[len(location)-1], err)
+		}
+		removed++
+	}
+	return removed, nil
+}
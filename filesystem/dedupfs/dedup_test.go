@@ -0,0 +1,210 @@
+// Copyright (C) 2025 ZedCloud Org.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package dedupfs_test
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pluto-org-co/fsio/filesystem/dedupfs"
+	"github.com/pluto-org-co/fsio/filesystem/directory"
+	"github.com/pluto-org-co/fsio/filesystem/subfs"
+	"github.com/pluto-org-co/fsio/filesystem/testsuite"
+	"github.com/pluto-org-co/fsio/filesystem/testsuite/samplesfiles"
+	"github.com/pluto-org-co/fsio/ioutils"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Dedup(t *testing.T) {
+	assertions := assert.New(t)
+
+	tempDir, err := os.MkdirTemp("", "*")
+	if !assertions.Nil(err, "failed to create temp") {
+		return
+	}
+	defer os.RemoveAll(tempDir)
+	localRoot := directory.New(tempDir, 0o777, 0o777)
+
+	dedupRoot := dedupfs.New(localRoot)
+
+	t.Run("Testsuite", testsuite.TestFilesystem(t, dedupRoot))
+	t.Run("Blobs", func(t *testing.T) {
+		assertions := assert.New(t)
+
+		ctx, cancel := context.WithTimeout(context.TODO(), time.Minute)
+		defer cancel()
+
+		localRoot := directory.New(t.TempDir(), 0o777, 0o777)
+		dedupRoot := dedupfs.New(localRoot)
+
+		countBlobs := func() (count int) {
+			for _, err := range subfs.New(localRoot, dedupfs.BlobsRoot).Files(ctx) {
+				if !assertions.Nil(err, "failed to list blobs") {
+					return -1
+				}
+				count++
+			}
+			return count
+		}
+
+		copies := [][]string{
+			{"users", "first", "report.txt"},
+			{"users", "second", "report-copy.txt"},
+			{"shared", "report.txt"},
+		}
+		for _, location := range copies {
+			_, err := dedupRoot.WriteFile(ctx, location, bytes.NewReader(samplesfiles.Lorem), time.Now())
+			if !assertions.Nil(err, "failed to write file") {
+				return
+			}
+		}
+		other := []string{"users", "second", "other.txt"}
+		_, err := dedupRoot.WriteFile(ctx, other, strings.NewReader("other contents"), time.Now())
+		if !assertions.Nil(err, "failed to write file") {
+			return
+		}
+		assertions.Equal(2, countBlobs(), "identical contents should be stored once")
+
+		for _, location := range copies {
+			file, err := dedupRoot.Open(ctx, location)
+			if !assertions.Nil(err, "failed to open file") {
+				return
+			}
+			contents, err := io.ReadAll(file)
+			file.Close()
+			if !assertions.Nil(err, "failed to read file") {
+				return
+			}
+			assertions.Equal(samplesfiles.Lorem, contents, "unexpected contents: %v", location)
+		}
+
+		info, err := dedupRoot.Stat(ctx, copies[1])
+		if !assertions.Nil(err, "failed to stat file") {
+			return
+		}
+		assertions.Equal(int64(len(samplesfiles.Lorem)), info.Size, "unexpected size")
+
+		t.Run("GarbageCollect", func(t *testing.T) {
+			assertions := assert.New(t)
+
+			removed, err := dedupRoot.GarbageCollect(ctx)
+			if !assertions.Nil(err, "failed to collect blobs") {
+				return
+			}
+			assertions.Zero(removed, "referenced blobs should be kept")
+
+			for _, location := range copies[:2] {
+				err = dedupRoot.RemoveAll(ctx, location)
+				if !assertions.Nil(err, "failed to remove file") {
+					return
+				}
+			}
+			_, err = dedupRoot.WriteFile(ctx, other, strings.NewReader("overwritten contents"), time.Now())
+			if !assertions.Nil(err, "failed to overwrite file") {
+				return
+			}
+
+			removed, err = dedupRoot.GarbageCollect(ctx)
+			if !assertions.Nil(err, "failed to collect blobs") {
+				return
+			}
+			assertions.Equal(int64(1), removed, "only the overwritten blob should be removed")
+			assertions.Equal(2, countBlobs(), "unexpected blobs")
+
+			_, err = dedupRoot.ChecksumSha256(ctx, copies[2])
+			assertions.Nil(err, "remaining copy should be kept")
+		})
+	})
+	t.Run("Office", func(t *testing.T) {
+		assertions := assert.New(t)
+
+		ctx, cancel := context.WithTimeout(context.TODO(), time.Minute)
+		defer cancel()
+
+		dedupRoot := dedupfs.New(directory.New(t.TempDir(), 0o777, 0o777))
+
+		// Documents only differing in parts ignored by ioutils.ChecksumSha256
+		document := func(header string) (contents []byte) {
+			var buffer bytes.Buffer
+			writer := zip.NewWriter(&buffer)
+			for _, part := range []struct{ Name, Contents string }{
+				{"[Content_Types].xml", `<?xml version="1.0"?><Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types"/>`},
+				{"word/document.xml", `<w:document><w:body>same body</w:body></w:document>`},
+				{"word/header1.xml", header},
+			} {
+				file, err := writer.Create(part.Name)
+				if !assertions.Nil(err, "failed to create zip entry") {
+					return nil
+				}
+				file.Write([]byte(part.Contents))
+			}
+			assertions.Nil(writer.Close(), "failed to close zip")
+			return buffer.Bytes()
+		}
+		first := document("<w:hdr>first header</w:hdr>")
+		second := document("<w:hdr>a much longer second header</w:hdr>")
+
+		firstChecksum, err := ioutils.ChecksumSha256(ctx, bytes.NewReader(first))
+		if !assertions.Nil(err, "failed to compute checksum") {
+			return
+		}
+		secondChecksum, err := ioutils.ChecksumSha256(ctx, bytes.NewReader(second))
+		if !assertions.Nil(err, "failed to compute checksum") {
+			return
+		}
+		if !assertions.Equal(firstChecksum, secondChecksum, "documents should share the normalized checksum") {
+			return
+		}
+
+		for name, contents := range map[string][]byte{"a.docx": first, "b.docx": second} {
+			_, err := dedupRoot.WriteFile(ctx, []string{name}, bytes.NewReader(contents), time.Now())
+			if !assertions.Nil(err, "failed to write file") {
+				return
+			}
+		}
+
+		for name, expected := range map[string][]byte{"a.docx": first, "b.docx": second} {
+			file, err := dedupRoot.Open(ctx, []string{name})
+			if !assertions.Nil(err, "failed to open file") {
+				return
+			}
+			contents, err := io.ReadAll(file)
+			file.Close()
+			if !assertions.Nil(err, "failed to read file") {
+				return
+			}
+			assertions.Equal(expected, contents, "unexpected contents: %s", name)
+
+			info, err := dedupRoot.Stat(ctx, []string{name})
+			if !assertions.Nil(err, "failed to stat file") {
+				return
+			}
+			assertions.Equal(int64(len(expected)), info.Size, "unexpected size: %s", name)
+
+			checksum, err := dedupRoot.ChecksumSha256(ctx, []string{name})
+			if !assertions.Nil(err, "failed to get checksum") {
+				return
+			}
+			assertions.Equal(firstChecksum, checksum, "checksum should stay normalized: %s", name)
+		}
+	})
+}
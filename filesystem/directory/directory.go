@@ -18,18 +18,23 @@ package directory
 import (
 	"bufio"
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"io/fs"
 	"iter"
+	"math/rand/v2"
+	"mime"
 	"os"
 	"path"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/charlievieth/fastwalk"
 	"github.com/pluto-org-co/fsio/filesystem"
+	"github.com/pluto-org-co/fsio/filesystem/utils"
 	"github.com/pluto-org-co/fsio/ioutils"
 )
 
@@ -37,7 +42,6 @@ type Directory struct {
 	dirPerm       fs.FileMode
 	filePerm      fs.FileMode
 	baseDirectory string
-	chdir         fs.FS
 }
 
 // Creates a new Local Filesystem, Root is the directory only have access to.
@@ -48,16 +52,73 @@ func New(root string, dirPerm, filePerm fs.FileMode) (l *Directory) {
 		filePerm:      filePerm,
 		dirPerm:       dirPerm,
 		baseDirectory: path.Clean(root),
-		chdir:         os.DirFS(root),
 	}
 }
 
-var _ filesystem.Filesystem = (*Directory)(nil)
+var (
+	_ filesystem.Filesystem  = (*Directory)(nil)
+	_ filesystem.RangeReader = (*Directory)(nil)
+	_ filesystem.Creator     = (*Directory)(nil)
+)
+
+// Opens the base directory. Every file operation is resolved through the returned root
+// so neither relative segments nor symlinks can reach files outside of it.
+// When create is set the base directory is created if missing
+func (l *Directory) openRoot(create bool) (root *os.Root, err error) {
+	if create {
+		err = os.MkdirAll(l.baseDirectory, l.dirPerm)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create base directory: %w", err)
+		}
+	}
+
+	root, err = os.OpenRoot(l.baseDirectory)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open base directory: %w", err)
+	}
+	return root, nil
+}
+
+// Relative name of the location inside the root
+func filenameOf(location []string) (filename string, err error) {
+	err = filesystem.ValidateLocation(location)
+	if err != nil {
+		return "", err
+	}
+	return path.Join(location...), nil
+}
+
+// Mirrors os.CreateTemp for files inside the root
+func createTemp(root *os.Root, dir, pattern string) (file *os.File, filename string, err error) {
+	prefix, suffix, _ := strings.Cut(pattern, "*")
+	for range 10_000 {
+		filename = path.Join(dir, prefix+strconv.FormatUint(uint64(rand.Uint32()), 10)+suffix)
+
+		file, err = root.OpenFile(filename, os.O_RDWR|os.O_CREATE|os.O_EXCL, 0o600)
+		if errors.Is(err, fs.ErrExist) {
+			continue
+		}
+		if err != nil {
+			return nil, "", err
+		}
+		return file, filename, nil
+	}
+	return nil, "", fmt.Errorf("no unused temporary name: %w", fs.ErrExist)
+}
 
 func (l *Directory) ChecksumTime(ctx context.Context, location []string) (checksum string, err error) {
-	filename := path.Join(l.baseDirectory, path.Clean(path.Join(location...)))
+	filename, err := filenameOf(location)
+	if err != nil {
+		return "", err
+	}
+
+	root, err := l.openRoot(false)
+	if err != nil {
+		return "", err
+	}
+	defer root.Close()
 
-	info, err := os.Stat(filename)
+	info, err := root.Stat(filename)
 	if err != nil {
 		return "", fmt.Errorf("failed to get file info: %w", err)
 	}
@@ -80,47 +141,135 @@ func (l *Directory) ChecksumSha256(ctx context.Context, location []string) (chec
 	return checksum, nil
 }
 
-func (l *Directory) Files(ctx context.Context) (seq iter.Seq[filesystem.FileEntry]) {
+// Contents are never read, the content type is guessed from the file extension
+// and is empty when the extension is unknown
+func (l *Directory) Stat(ctx context.Context, location []string) (info *filesystem.FileInfo, err error) {
+	filename, err := filenameOf(location)
+	if err != nil {
+		return nil, err
+	}
+
+	root, err := l.openRoot(false)
+	if err != nil {
+		return nil, err
+	}
+	defer root.Close()
+
+	fileInfo, err := root.Stat(filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get file info: %w", err)
+	}
+
+	if !fileInfo.Mode().IsRegular() {
+		return nil, fmt.Errorf("not a regular file: %s: %w", filename, fs.ErrNotExist)
+	}
+
+	info = &filesystem.FileInfo{
+		Location:    location,
+		Size:        fileInfo.Size(),
+		ModTime:     fileInfo.ModTime(),
+		ContentType: mime.TypeByExtension(path.Ext(filename)),
+	}
+	return info, nil
+}
+
+type dirResult struct {
+	entry *filesystem.SimpleFileEntry
+	err   error
+}
+
+func (l *Directory) Files(ctx context.Context, options ...filesystem.ListOption) (seq iter.Seq2[filesystem.FileEntry, error]) {
+	listCtx := filesystem.NewListCtx(options...)
+
+	err := filesystem.ValidatePrefix(listCtx.Prefix)
+	if err != nil {
+		return func(yield func(filesystem.FileEntry, error) bool) {
+			yield(nil, err)
+		}
+	}
+
 	conf := fastwalk.DefaultConfig
+	root := path.Join(l.baseDirectory, path.Clean(path.Join(listCtx.Prefix...)))
 
-	worker := make(chan *filesystem.SimpleFileEntry, 10_000)
+	worker := make(chan *dirResult, 10_000)
 	closeCh := make(chan struct{}, 1)
 	go func() {
 		defer close(worker)
 
-		fastwalk.Walk(&conf, l.baseDirectory, func(fileLocation string, d fs.DirEntry, err error) error {
+		// Listing a missing prefix is the same as listing an empty directory
+		_, err := os.Stat(root)
+		if errors.Is(err, fs.ErrNotExist) {
+			return
+		}
+
+		err = fastwalk.Walk(&conf, root, func(fileLocation string, d fs.DirEntry, err error) error {
 			select {
 			case <-ctx.Done():
 				return ctx.Err()
 			case <-closeCh:
 				return io.EOF
 			default:
+				if err != nil {
+					return err
+				}
+
 				info, err := d.Info()
+				if errors.Is(err, fs.ErrNotExist) {
+					// Removed during the walk
+					return nil
+				}
 				if err != nil {
 					return fmt.Errorf("failed to get file info: %w", err)
 				}
 
-				if info.IsDir() || !info.Mode().IsRegular() {
+				if info.IsDir() {
+					if !listCtx.Recursive && fileLocation != root {
+						return fastwalk.SkipDir
+					}
+					return nil
+				}
+
+				if !info.Mode().IsRegular() {
 					return nil
 				}
 
 				filename, _ := filepath.Rel(l.baseDirectory, fileLocation)
 
-				worker <- &filesystem.SimpleFileEntry{
-					LocationValue: strings.Split(filename, "/"),
-					ModTimeValue:  info.ModTime(),
+				result := &dirResult{
+					entry: &filesystem.SimpleFileEntry{
+						LocationValue: strings.Split(filename, "/"),
+						ModTimeValue:  info.ModTime(),
+						SizeValue:     info.Size(),
+						SysValue:      info,
+					},
+				}
+				select {
+				case worker <- result:
+					return nil
+				case <-closeCh:
+					return io.EOF
 				}
-				return nil
 			}
 		})
+		if err != nil && !errors.Is(err, io.EOF) {
+			select {
+			case worker <- &dirResult{err: fmt.Errorf("failed to walk directory: %w", err)}:
+			case <-closeCh:
+			}
+		}
 	}()
-	return func(yield func(filesystem.FileEntry) bool) {
+	return func(yield func(filesystem.FileEntry, error) bool) {
 		defer func() {
 			closeCh <- struct{}{}
 			close(closeCh)
 		}()
-		for entry := range worker {
-			if !yield(entry) {
+		for result := range worker {
+			if result.err != nil {
+				yield(nil, result.err)
+				return
+			}
+
+			if !yield(result.entry, nil) {
 				return
 			}
 		}
@@ -128,16 +277,63 @@ func (l *Directory) Files(ctx context.Context) (seq iter.Seq[filesystem.FileEntr
 }
 
 func (l *Directory) Open(_ context.Context, location []string) (rc io.ReadCloser, err error) {
-	filename := path.Join(location...)
-	return l.chdir.Open(filename)
+	filename, err := filenameOf(location)
+	if err != nil {
+		return nil, err
+	}
+
+	root, err := l.openRoot(false)
+	if err != nil {
+		return nil, err
+	}
+	defer root.Close()
+
+	file, err := root.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	return file, nil
+}
+
+func (l *Directory) OpenRange(ctx context.Context, location []string, offset, length int64) (rc io.ReadCloser, err error) {
+	if offset < 0 {
+		return nil, fmt.Errorf("invalid offset: %d: %w", offset, fs.ErrInvalid)
+	}
+
+	file, err := l.Open(ctx, location)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open file: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			file.Close()
+		}
+	}()
+	osFile := file.(*os.File)
+
+	if length < 0 {
+		info, err := osFile.Stat()
+		if err != nil {
+			return nil, fmt.Errorf("failed to get file info: %w", err)
+		}
+		length = max(info.Size()-offset, 0)
+	}
+
+	rc = utils.NewSeparateReadCloser(osFile, io.NewSectionReader(osFile, offset, length))
+	return rc, nil
 }
 
 func (l *Directory) WriteFile(ctx context.Context, location []string, src io.Reader, modTime time.Time) (finalLocation []string, err error) {
-	filename := path.Join(l.baseDirectory, path.Clean(path.Join(location...)))
-	filename = path.Clean(filename)
+	filename, err := filenameOf(location)
+	if err != nil {
+		return location, err
+	}
 
-	basedir, _ := path.Split(filename)
-	basedir = path.Clean(basedir)
+	root, err := l.openRoot(true)
+	if err != nil {
+		return location, err
+	}
+	defer root.Close()
 
 	// Create directory location
 	select {
@@ -148,7 +344,7 @@ func (l *Directory) WriteFile(ctx context.Context, location []string, src io.Rea
 		}
 		return location, nil
 	default:
-		err = os.MkdirAll(basedir, l.dirPerm)
+		err = root.MkdirAll(path.Dir(filename), l.dirPerm)
 		if err != nil {
 			return location, fmt.Errorf("failed to create file directory: %w", err)
 		}
@@ -164,7 +360,7 @@ func (l *Directory) WriteFile(ctx context.Context, location []string, src io.Rea
 		}
 		return location, nil
 	default:
-		file, err = os.OpenFile(filename, os.O_CREATE|os.O_WRONLY, l.filePerm)
+		file, err = root.OpenFile(filename, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, l.filePerm)
 		if err != nil {
 			return location, fmt.Errorf("failed to create dst file: %w", err)
 		}
@@ -175,7 +371,7 @@ func (l *Directory) WriteFile(ctx context.Context, location []string, src io.Rea
 			if err == nil {
 				return
 			}
-			os.Remove(file.Name())
+			root.Remove(filename)
 		}()
 	}
 
@@ -199,30 +395,189 @@ func (l *Directory) WriteFile(ctx context.Context, location []string, src io.Rea
 		return nil, fmt.Errorf("failed to flush changes: %w", err)
 	}
 
-	err = os.Chtimes(file.Name(), time.Now(), modTime)
+	err = root.Chtimes(filename, time.Now(), modTime)
 	if err != nil {
 		return nil, fmt.Errorf("failed to set new mod time: %w", err)
 	}
 	return location, nil
 }
 
+type fileWriter struct {
+	ctx      context.Context
+	location []string
+	root     *os.Root
+	file     *os.File
+	buffer   *bufio.Writer
+	tempname string
+	filename string
+	modTime  time.Time
+	done     bool
+}
+
+var _ filesystem.FileWriter = (*fileWriter)(nil)
+
+func (w *fileWriter) Write(b []byte) (n int, err error) {
+	err = w.ctx.Err()
+	if err != nil {
+		return 0, fmt.Errorf("context error during write: %w", err)
+	}
+	return w.buffer.Write(b)
+}
+
+func (w *fileWriter) Close() (err error) {
+	if w.done {
+		return nil
+	}
+	w.done = true
+	defer w.root.Close()
+
+	defer func() {
+		if err != nil {
+			w.file.Close()
+			w.root.Remove(w.tempname)
+		}
+	}()
+
+	err = w.ctx.Err()
+	if err != nil {
+		return fmt.Errorf("context error during commit: %w", err)
+	}
+
+	err = w.buffer.Flush()
+	if err != nil {
+		return fmt.Errorf("failed to flush changes: %w", err)
+	}
+
+	err = w.file.Close()
+	if err != nil {
+		return fmt.Errorf("failed to close temporary file: %w", err)
+	}
+
+	err = w.root.Chtimes(w.tempname, time.Now(), w.modTime)
+	if err != nil {
+		return fmt.Errorf("failed to set new mod time: %w", err)
+	}
+
+	err = w.root.Rename(w.tempname, w.filename)
+	if err != nil {
+		return fmt.Errorf("failed to rename temporary file: %w", err)
+	}
+	return nil
+}
+
+func (w *fileWriter) Location() (location []string) {
+	return w.location
+}
+
+func (w *fileWriter) Abort() (err error) {
+	if w.done {
+		return nil
+	}
+	w.done = true
+	defer w.root.Close()
+
+	w.file.Close()
+	err = w.root.Remove(w.tempname)
+	if err != nil {
+		return fmt.Errorf("failed to remove temporary file: %w", err)
+	}
+	return nil
+}
+
+// Writes into a temporary file next to the target, renamed into place on Close
+func (l *Directory) Create(ctx context.Context, location []string, modTime time.Time) (w filesystem.FileWriter, err error) {
+	filename, err := filenameOf(location)
+	if err != nil {
+		return nil, err
+	}
+
+	root, err := l.openRoot(true)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err != nil {
+			root.Close()
+		}
+	}()
+
+	basedir, base := path.Split(filename)
+	basedir = path.Clean(basedir)
+	err = root.MkdirAll(basedir, l.dirPerm)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create file directory: %w", err)
+	}
+
+	file, tempname, err := createTemp(root, basedir, "."+base+".*.tmp")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create temporary file: %w", err)
+	}
+
+	err = file.Chmod(l.filePerm)
+	if err != nil {
+		file.Close()
+		root.Remove(tempname)
+		return nil, fmt.Errorf("failed to set file permissions: %w", err)
+	}
+
+	w = &fileWriter{
+		ctx:      ctx,
+		location: location,
+		root:     root,
+		file:     file,
+		buffer:   bufio.NewWriterSize(file, ioutils.DefaultBufferSize),
+		tempname: tempname,
+		filename: filename,
+		modTime:  modTime,
+	}
+	return w, nil
+}
+
+// Removes the file or the directory with all of its contents. Missing files are ignored
 func (l *Directory) RemoveAll(ctx context.Context, location []string) (err error) {
-	filename := path.Join(l.baseDirectory, path.Clean(path.Join(location...)))
+	filename, err := filenameOf(location)
+	if err != nil {
+		return err
+	}
+
+	root, err := l.openRoot(false)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer root.Close()
 
-	return os.Remove(filename)
+	err = root.RemoveAll(filename)
+	if err != nil {
+		return fmt.Errorf("failed to remove file: %w", err)
+	}
+	return nil
 }
 
 func (l *Directory) Move(ctx context.Context, oldLocation, newLocation []string) (finalLocation []string, err error) {
-	oldFilename := path.Join(l.baseDirectory, path.Clean(path.Join(oldLocation...)))
-	newFilename := path.Join(l.baseDirectory, path.Clean(path.Join(newLocation...)))
+	oldFilename, err := filenameOf(oldLocation)
+	if err != nil {
+		return nil, err
+	}
+	newFilename, err := filenameOf(newLocation)
+	if err != nil {
+		return nil, err
+	}
+
+	root, err := l.openRoot(false)
+	if err != nil {
+		return nil, err
+	}
+	defer root.Close()
 
-	newDir, _ := path.Split(newFilename)
-	err = os.MkdirAll(newDir, l.dirPerm)
+	err = root.MkdirAll(path.Dir(newFilename), l.dirPerm)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create directory: %w", err)
 	}
 
-	err = os.Rename(oldFilename, newFilename)
+	err = root.Rename(oldFilename, newFilename)
 	if err != nil {
 		return nil, fmt.Errorf("failed to rename file: %w", err)
 	}
@@ -16,8 +16,13 @@
 package directory_test
 
 import (
+	"context"
+	"io/fs"
 	"os"
+	"path"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/pluto-org-co/fsio/filesystem/directory"
 	"github.com/pluto-org-co/fsio/filesystem/testsuite"
@@ -36,4 +41,60 @@ func Test_Directory(t *testing.T) {
 
 	t.Run("Testsuite", testsuite.TestFilesystem(t, localRoot))
 
+	t.Run("SymlinkEscape", func(t *testing.T) {
+		assertions := assert.New(t)
+
+		outsideDir, err := os.MkdirTemp("", "*")
+		if !assertions.Nil(err, "failed to create outside temp") {
+			return
+		}
+		defer os.RemoveAll(outsideDir)
+
+		err = os.WriteFile(path.Join(outsideDir, "secret.txt"), []byte("secret"), 0o600)
+		if !assertions.Nil(err, "failed to write outside file") {
+			return
+		}
+
+		err = os.Symlink(outsideDir, path.Join(tempDir, "link"))
+		if !assertions.Nil(err, "failed to create symlink") {
+			return
+		}
+		defer os.Remove(path.Join(tempDir, "link"))
+
+		ctx, cancel := context.WithTimeout(context.TODO(), time.Minute)
+		defer cancel()
+
+		_, err = localRoot.Open(ctx, []string{"link", "secret.txt"})
+		assertions.NotNil(err, "should not open files outside the root")
+
+		_, err = localRoot.WriteFile(ctx, []string{"link", "written.txt"}, strings.NewReader("escaped"), time.Now())
+		assertions.NotNil(err, "should not write files outside the root")
+
+		_, err = os.Stat(path.Join(outsideDir, "written.txt"))
+		assertions.ErrorIs(err, fs.ErrNotExist, "file should not be created outside the root")
+	})
+	t.Run("StatUnreadable", func(t *testing.T) {
+		assertions := assert.New(t)
+
+		if os.Getuid() == 0 {
+			t.Skip("Can't run this test as root")
+			return
+		}
+
+		err := os.WriteFile(path.Join(tempDir, "unreadable.txt"), []byte("contents"), 0o000)
+		if !assertions.Nil(err, "failed to write unreadable file") {
+			return
+		}
+		defer os.Remove(path.Join(tempDir, "unreadable.txt"))
+
+		ctx, cancel := context.WithTimeout(context.TODO(), time.Minute)
+		defer cancel()
+
+		info, err := localRoot.Stat(ctx, []string{"unreadable.txt"})
+		if !assertions.Nil(err, "stat should not read the contents") {
+			return
+		}
+		assertions.Equal(int64(len("contents")), info.Size, "invalid size")
+		assertions.True(strings.HasPrefix(info.ContentType, "text/plain"), "content type should be guessed from the extension")
+	})
 }
@@ -0,0 +1,96 @@
+// Copyright (C) 2025 ZedCloud Org.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package filesystem
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net"
+	"syscall"
+)
+
+// Returned by filesystems when the requested operation is not available for the backend.
+// It wraps errors.ErrUnsupported so both sentinels can be used with errors.Is
+var ErrUnsupported = fmt.Errorf("operation not supported: %w", errors.ErrUnsupported)
+
+// Returned by filesystems when the backend rejected the operation due to quota or rate limits.
+// Missing files and denied access are reported with fs.ErrNotExist and fs.ErrPermission
+var ErrRateLimited = errors.New("quota exceeded or rate limited")
+
+// Returned by filesystems when the backend failed temporarily, like server errors.
+// The operation may succeed if attempted again
+var ErrTransient = errors.New("transient failure")
+
+// Returned by copies and syncs when the source listing failed before reaching every file.
+// The files found before the failure are still processed
+var ErrIncompleteListing = errors.New("incomplete listing")
+
+// Returned by copies and syncs when the src file was removed after being listed, so it can be ignored.
+// Missing dst files and buckets are failures instead
+var ErrSrcNotExist = fmt.Errorf("src file no longer exists: %w", fs.ErrNotExist)
+
+// Tags the errors reporting a missing src file with ErrSrcNotExist
+func srcError(err error) (tagged error) {
+	if errors.Is(err, fs.ErrNotExist) && !errors.Is(err, ErrSrcNotExist) {
+		return fmt.Errorf("%w: %w", ErrSrcNotExist, err)
+	}
+	return err
+}
+
+// Returned by mirrors when the files missing from the source exceed the allowed share of the destination
+var ErrTooManyDeletions = errors.New("too many deletions")
+
+// How the failure of a single file should be handled during copies and syncs
+type ErrorAction int
+
+const (
+	// The operation failed for this file only
+	ErrorActionFail ErrorAction = iota
+	// The src file no longer exists and can be ignored
+	ErrorActionSkip
+	// The operation may succeed if attempted later
+	ErrorActionRetry
+	// The operation can't succeed for any other file either
+	ErrorActionAbort
+)
+
+// Classifies the error returned by a filesystem operation
+func ClassifyError(err error) (action ErrorAction) {
+	switch {
+	case errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded):
+		return ErrorActionAbort
+	case errors.Is(err, ErrSrcNotExist):
+		return ErrorActionSkip
+	case errors.Is(err, ErrRateLimited) || errors.Is(err, ErrTransient) || isNetworkFailure(err):
+		return ErrorActionRetry
+	default:
+		return ErrorActionFail
+	}
+}
+
+// Reports connections reset or timed out while talking with the backend
+func isNetworkFailure(err error) (ok bool) {
+	if errors.Is(err, syscall.ECONNRESET) || errors.Is(err, syscall.ECONNABORTED) || errors.Is(err, syscall.EPIPE) ||
+		errors.Is(err, io.ErrUnexpectedEOF) {
+		return true
+	}
+
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
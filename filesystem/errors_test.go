@@ -0,0 +1,56 @@
+// Copyright (C) 2025 ZedCloud Org.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package filesystem_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"syscall"
+	"testing"
+
+	"github.com/pluto-org-co/fsio/filesystem"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_ClassifyError(t *testing.T) {
+	type Test struct {
+		Name     string
+		Err      error
+		Expected filesystem.ErrorAction
+	}
+	var tests = []Test{
+		{Name: "SrcNotExist", Err: fmt.Errorf("failed to open: %w", filesystem.ErrSrcNotExist), Expected: filesystem.ErrorActionSkip},
+		{Name: "NotExist", Err: fmt.Errorf("failed to write: %w", fs.ErrNotExist), Expected: filesystem.ErrorActionFail},
+		{Name: "RateLimited", Err: fmt.Errorf("failed to open: %w", filesystem.ErrRateLimited), Expected: filesystem.ErrorActionRetry},
+		{Name: "Transient", Err: fmt.Errorf("failed to open: %w", filesystem.ErrTransient), Expected: filesystem.ErrorActionRetry},
+		{Name: "ConnectionReset", Err: fmt.Errorf("failed to read: %w", syscall.ECONNRESET), Expected: filesystem.ErrorActionRetry},
+		{Name: "UnexpectedEOF", Err: fmt.Errorf("failed to read: %w", io.ErrUnexpectedEOF), Expected: filesystem.ErrorActionRetry},
+		{Name: "Deadline", Err: fmt.Errorf("failed to open: %w", context.DeadlineExceeded), Expected: filesystem.ErrorActionAbort},
+		{Name: "Permission", Err: fmt.Errorf("failed to open: %w", fs.ErrPermission), Expected: filesystem.ErrorActionFail},
+		{Name: "Other", Err: errors.New("unknown"), Expected: filesystem.ErrorActionFail},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			assertions := assert.New(t)
+
+			assertions.Equal(test.Expected, filesystem.ClassifyError(test.Err), "unexpected action")
+		})
+	}
+}
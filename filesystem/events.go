@@ -0,0 +1,74 @@
+// Copyright (C) 2025 ZedCloud Org.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package filesystem
+
+import (
+	"sync"
+)
+
+// Progress reported to sync observers
+type EventKind string
+
+const (
+	// A src file was listed. Bytes holds its size, negative when unknown
+	EventListed EventKind = "listed"
+	// The action on the file started
+	EventStarted EventKind = "started"
+	// Bytes holds the amount of bytes of the file copied so far
+	EventProgress EventKind = "progress"
+	// The file was up to date or no longer exists, in which case Err holds the reason
+	EventSkipped EventKind = "skipped"
+	// The action on the file failed with Err and will be attempted again
+	EventRetry EventKind = "retry"
+	// The action on the file failed with Err
+	EventFailed EventKind = "failed"
+	// The action on the file succeeded. Bytes holds the bytes copied
+	EventCompleted EventKind = "completed"
+	// The sync finished with Err. Result holds its summary
+	EventFinished EventKind = "finished"
+)
+
+type Event struct {
+	Kind EventKind
+	// Action performed on the file, empty for listing and sync events
+	Action   ActionKind
+	Location []string
+	Bytes    int64
+	Err      error
+	Result   *SyncResult
+}
+
+// Receives the events of a sync. Observers of a single sync are never called concurrently,
+// so they should return quickly for not slowing down the workers
+type Observer func(event Event)
+
+type observers struct {
+	mutex     sync.Mutex
+	observers []Observer
+}
+
+func (o *observers) notify(event Event) {
+	if o == nil || len(o.observers) == 0 {
+		return
+	}
+
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+
+	for _, observer := range o.observers {
+		observer(event)
+	}
+}
@@ -17,7 +17,6 @@ package filesystem
 
 import (
 	"context"
-	"io"
 	"iter"
 	"time"
 )
@@ -25,6 +24,12 @@ import (
 type SimpleFileEntry struct {
 	LocationValue []string
 	ModTimeValue  time.Time
+	// Negative when unknown
+	SizeValue int64
+	// Empty when unknown
+	ContentTypeValue string
+	// Raw entry returned by the backend
+	SysValue any
 }
 
 var _ FileEntry = (*SimpleFileEntry)(nil)
@@ -37,9 +42,43 @@ func (f *SimpleFileEntry) ModTime() (mtime time.Time) {
 	return f.ModTimeValue
 }
 
+func (f *SimpleFileEntry) Size() (size int64) {
+	return f.SizeValue
+}
+
+func (f *SimpleFileEntry) ContentType() (contentType string) {
+	return f.ContentTypeValue
+}
+
+func (f *SimpleFileEntry) Sys() (sys any) {
+	return f.SysValue
+}
+
 type FileEntry interface {
 	Location() (location []string)
 	ModTime() (mtime time.Time)
+	// Size in bytes of the file. Negative when the backend can't know it without reading the contents
+	Size() (size int64)
+	// Content type of the file, empty when unknown
+	ContentType() (contentType string)
+	// Raw entry returned by the backend listing. For example minio.ObjectInfo or *drive.File
+	Sys() (sys any)
+}
+
+// Metadata of a single file as reported by the backend
+type FileInfo struct {
+	Location []string
+	// Size in bytes of the file. Negative when the backend can't know it without reading the contents
+	Size    int64
+	ModTime time.Time
+	// Content type of the file, empty when unknown
+	ContentType string
+	// Backend specific content tag. For example the S3 ETag or the Google Drive md5 checksum
+	ETag string
+	// Backend specific version of the file
+	Version string
+	// Backend specific identifier of the file
+	ID string
 }
 
 type Filesystem interface {
@@ -47,16 +86,14 @@ type Filesystem interface {
 	ChecksumTime(ctx context.Context, location []string) (checksum string, err error)
 	// Returns the unique sha256 checksum of the file provided
 	ChecksumSha256(ctx context.Context, location []string) (checksum string, err error)
-	// Returns the seq of all available files in the filesystem
-	Files(ctx context.Context) (seq iter.Seq[FileEntry])
-	// Opens a reader for the passed file.
-	Open(ctx context.Context, location []string) (rc io.ReadCloser, err error)
-	// Writes the reader to the dst filePath.
-	// Returned filename is the actual name used during the write. Done this way since some implementation may alter the file name during
-	// normalization
-	WriteFile(ctx context.Context, location []string, src io.Reader, modTime time.Time) (finalLocation []string, err error)
-	// Remove the path from the filesystem
-	RemoveAll(ctx context.Context, location []string) (err error)
-	// Move a file to a new location
-	Move(ctx context.Context, oldLocation, newLocation []string) (finalLocation []string, err error)
+	// Returns the metadata of the file provided
+	Stat(ctx context.Context, location []string) (info *FileInfo, err error)
+	// Returns the seq of all available files in the filesystem.
+	// Options allow to restrict the listing to a location prefix.
+	// A listing failure is yielded as a non nil error with a nil entry and ends the sequence
+	Files(ctx context.Context, options ...ListOption) (seq iter.Seq2[FileEntry, error])
+	Reader
+	Writer
+	Remover
+	Mover
 }
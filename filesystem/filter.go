@@ -0,0 +1,305 @@
+// Copyright (C) 2025 ZedCloud Org.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package filesystem
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/pluto-org-co/fsio/ioutils"
+)
+
+// Decides which listed files take part in a listing or a sync.
+// Files must match every configured rule. Rules can't exclude files with an unknown size, content type or modification time
+type Filter struct {
+	// When not empty, files must match at least one of the patterns
+	Include []Pattern
+	// Files matching any of the patterns are excluded
+	Exclude []Pattern
+	// Minimum size in bytes. Zero disables the bound
+	MinSize int64
+	// Maximum size in bytes. Zero disables the bound
+	MaxSize int64
+	// When not empty, files must have one of the content types
+	ContentTypes []string
+	// Files with any of the content types are excluded
+	ExcludeContentTypes []string
+	// Files modified more recently are excluded. Zero disables the bound
+	MinAge time.Duration
+	// Files modified longer ago are excluded. Zero disables the bound
+	MaxAge time.Duration
+}
+
+// Matches the location of a file
+type Pattern interface {
+	Match(location []string) (ok bool)
+}
+
+type globPattern string
+
+// Patterns without a slash are matched against every segment of the location.
+// Patterns with a slash are matched against the leading segments of the location, so they also match the files inside a directory
+func (g globPattern) Match(location []string) (ok bool) {
+	if !strings.Contains(string(g), "/") {
+		for _, segment := range location {
+			ok, _ = path.Match(string(g), segment)
+			if ok {
+				return true
+			}
+		}
+		return false
+	}
+
+	for index := range location {
+		ok, _ = path.Match(string(g), path.Join(location[:index+1]...))
+		if ok {
+			return true
+		}
+	}
+	return false
+}
+
+// Glob pattern following the path.Match syntax
+func Glob(pattern string) (p Pattern, err error) {
+	_, err = path.Match(pattern, "")
+	if err != nil {
+		return nil, fmt.Errorf("invalid glob: %q: %w", pattern, err)
+	}
+	return globPattern(pattern), nil
+}
+
+type regexPattern struct {
+	expression *regexp.Regexp
+}
+
+// The expression is matched against the slash joined location
+func (r *regexPattern) Match(location []string) (ok bool) {
+	return r.expression.MatchString(path.Join(location...))
+}
+
+// Regular expression following the regexp syntax
+func Regex(expression string) (p Pattern, err error) {
+	compiled, err := regexp.Compile(expression)
+	if err != nil {
+		return nil, fmt.Errorf("invalid regex: %w", err)
+	}
+	return &regexPattern{expression: compiled}, nil
+}
+
+type FilterOption func(filter *Filter) (err error)
+
+func patternsOf(compile func(string) (Pattern, error), values []string) (patterns []Pattern, err error) {
+	patterns = make([]Pattern, 0, len(values))
+	for _, value := range values {
+		pattern, err := compile(value)
+		if err != nil {
+			return nil, err
+		}
+		patterns = append(patterns, pattern)
+	}
+	return patterns, nil
+}
+
+// Only includes the files matching any of the glob patterns
+func WithFilterOptionInclude(patterns ...string) (option FilterOption) {
+	return func(filter *Filter) (err error) {
+		globs, err := patternsOf(Glob, patterns)
+		if err != nil {
+			return err
+		}
+		filter.Include = append(filter.Include, globs...)
+		return nil
+	}
+}
+
+// Excludes the files matching any of the glob patterns
+func WithFilterOptionExclude(patterns ...string) (option FilterOption) {
+	return func(filter *Filter) (err error) {
+		globs, err := patternsOf(Glob, patterns)
+		if err != nil {
+			return err
+		}
+		filter.Exclude = append(filter.Exclude, globs...)
+		return nil
+	}
+}
+
+// Only includes the files matching any of the regular expressions
+func WithFilterOptionIncludeRegex(expressions ...string) (option FilterOption) {
+	return func(filter *Filter) (err error) {
+		regexes, err := patternsOf(Regex, expressions)
+		if err != nil {
+			return err
+		}
+		filter.Include = append(filter.Include, regexes...)
+		return nil
+	}
+}
+
+// Excludes the files matching any of the regular expressions
+func WithFilterOptionExcludeRegex(expressions ...string) (option FilterOption) {
+	return func(filter *Filter) (err error) {
+		regexes, err := patternsOf(Regex, expressions)
+		if err != nil {
+			return err
+		}
+		filter.Exclude = append(filter.Exclude, regexes...)
+		return nil
+	}
+}
+
+// Size bounds in bytes. Zero disables a bound
+func WithFilterOptionSize(minSize, maxSize int64) (option FilterOption) {
+	return func(filter *Filter) (err error) {
+		if minSize < 0 || maxSize < 0 || (maxSize > 0 && minSize > maxSize) {
+			return fmt.Errorf("invalid size bounds: %d-%d", minSize, maxSize)
+		}
+		filter.MinSize = minSize
+		filter.MaxSize = maxSize
+		return nil
+	}
+}
+
+// Only includes the files with any of the content types.
+// Accepts the group names of ioutils.MimeTypeGroups and path.Match patterns like video/*
+func WithFilterOptionContentTypes(contentTypes ...string) (option FilterOption) {
+	return func(filter *Filter) (err error) {
+		contentTypes = ioutils.ExpandMimeTypes(contentTypes)
+		err = validateContentTypes(contentTypes)
+		if err != nil {
+			return err
+		}
+		filter.ContentTypes = append(filter.ContentTypes, contentTypes...)
+		return nil
+	}
+}
+
+// Excludes the files with any of the content types.
+// Accepts the group names of ioutils.MimeTypeGroups and path.Match patterns like video/*
+func WithFilterOptionExcludeContentTypes(contentTypes ...string) (option FilterOption) {
+	return func(filter *Filter) (err error) {
+		contentTypes = ioutils.ExpandMimeTypes(contentTypes)
+		err = validateContentTypes(contentTypes)
+		if err != nil {
+			return err
+		}
+		filter.ExcludeContentTypes = append(filter.ExcludeContentTypes, contentTypes...)
+		return nil
+	}
+}
+
+func validateContentTypes(contentTypes []string) (err error) {
+	for _, contentType := range contentTypes {
+		_, err = path.Match(contentType, "")
+		if err != nil {
+			return fmt.Errorf("invalid content type: %q: %w", contentType, err)
+		}
+	}
+	return nil
+}
+
+// Age bounds of the modification time. Zero disables a bound
+func WithFilterOptionAge(minAge, maxAge time.Duration) (option FilterOption) {
+	return func(filter *Filter) (err error) {
+		if minAge < 0 || maxAge < 0 || (maxAge > 0 && minAge > maxAge) {
+			return fmt.Errorf("invalid age bounds: %s-%s", minAge, maxAge)
+		}
+		filter.MinAge = minAge
+		filter.MaxAge = maxAge
+		return nil
+	}
+}
+
+// Prepares the filter from the passed options. Without options every file is included
+func NewFilter(options ...FilterOption) (filter *Filter, err error) {
+	filter = &Filter{}
+	for _, option := range options {
+		err = option(filter)
+		if err != nil {
+			return nil, fmt.Errorf("failed to apply filter option: %w", err)
+		}
+	}
+	return filter, nil
+}
+
+func matchAny(patterns []Pattern, location []string) (ok bool) {
+	for _, pattern := range patterns {
+		if pattern.Match(location) {
+			return true
+		}
+	}
+	return false
+}
+
+func matchContentType(contentTypes []string, contentType string) (ok bool) {
+	for _, pattern := range contentTypes {
+		ok, _ = path.Match(pattern, contentType)
+		if ok {
+			return true
+		}
+	}
+	return false
+}
+
+// Reports if the listed file passes the filter. A nil filter includes every file
+func (f *Filter) Match(entry FileEntry) (ok bool) {
+	if f == nil {
+		return true
+	}
+
+	location := entry.Location()
+	if len(f.Include) > 0 && !matchAny(f.Include, location) {
+		return false
+	}
+	if matchAny(f.Exclude, location) {
+		return false
+	}
+
+	size := entry.Size()
+	if size >= 0 {
+		if f.MinSize > 0 && size < f.MinSize {
+			return false
+		}
+		if f.MaxSize > 0 && size > f.MaxSize {
+			return false
+		}
+	}
+
+	contentType := entry.ContentType()
+	if contentType != "" {
+		if len(f.ContentTypes) > 0 && !matchContentType(f.ContentTypes, contentType) {
+			return false
+		}
+		if matchContentType(f.ExcludeContentTypes, contentType) {
+			return false
+		}
+	}
+
+	modTime := entry.ModTime()
+	if !modTime.IsZero() {
+		age := time.Since(modTime)
+		if f.MinAge > 0 && age < f.MinAge {
+			return false
+		}
+		if f.MaxAge > 0 && age > f.MaxAge {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,138 @@
+// Copyright (C) 2025 ZedCloud Org.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package filesystem_test
+
+import (
+	"context"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pluto-org-co/fsio/filesystem"
+	"github.com/pluto-org-co/fsio/filesystem/directory"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Filter(t *testing.T) {
+	t.Run("Match", func(t *testing.T) {
+		now := time.Now()
+		entry := func(location string, size int64, contentType string, modTime time.Time) (entry filesystem.FileEntry) {
+			return &filesystem.SimpleFileEntry{
+				LocationValue:    strings.Split(location, "/"),
+				SizeValue:        size,
+				ContentTypeValue: contentType,
+				ModTimeValue:     modTime,
+			}
+		}
+
+		type Test struct {
+			Name    string
+			Options []filesystem.FilterOption
+			Entry   filesystem.FileEntry
+			Expect  bool
+		}
+		tests := []Test{
+			{Name: "Empty", Entry: entry("docs/report.txt", 10, "text/plain", now), Expect: true},
+			{Name: "Exclude Segment", Options: []filesystem.FilterOption{filesystem.WithFilterOptionExclude("~$*")}, Entry: entry("docs/~$report.docx", 10, "", now), Expect: false},
+			{Name: "Exclude Directory", Options: []filesystem.FilterOption{filesystem.WithFilterOptionExclude("Videos")}, Entry: entry("users/Videos/movie.txt", 10, "", now), Expect: false},
+			{Name: "Include Path", Options: []filesystem.FilterOption{filesystem.WithFilterOptionInclude("domains/*/users/alice")}, Entry: entry("domains/example.com/users/alice/files/report.txt", 10, "", now), Expect: true},
+			{Name: "Include Path Other", Options: []filesystem.FilterOption{filesystem.WithFilterOptionInclude("domains/*/users/alice")}, Entry: entry("domains/example.com/users/bob/files/report.txt", 10, "", now), Expect: false},
+			{Name: "Exclude Regex", Options: []filesystem.FilterOption{filesystem.WithFilterOptionExcludeRegex(`\.(mp4|mkv)$`)}, Entry: entry("docs/movie.mkv", 10, "", now), Expect: false},
+			{Name: "Include Regex", Options: []filesystem.FilterOption{filesystem.WithFilterOptionIncludeRegex(`^docs/`)}, Entry: entry("docs/report.txt", 10, "", now), Expect: true},
+			{Name: "Max Size", Options: []filesystem.FilterOption{filesystem.WithFilterOptionSize(0, 5)}, Entry: entry("docs/report.txt", 10, "", now), Expect: false},
+			{Name: "Min Size", Options: []filesystem.FilterOption{filesystem.WithFilterOptionSize(20, 0)}, Entry: entry("docs/report.txt", 10, "", now), Expect: false},
+			{Name: "Unknown Size", Options: []filesystem.FilterOption{filesystem.WithFilterOptionSize(0, 5)}, Entry: entry("docs/report.txt", -1, "", now), Expect: true},
+			{Name: "Content Type Group", Options: []filesystem.FilterOption{filesystem.WithFilterOptionContentTypes("office-like")}, Entry: entry("docs/report.docx", 10, "application/vnd.openxmlformats-officedocument.wordprocessingml.document", now), Expect: true},
+			{Name: "Content Type Group Other", Options: []filesystem.FilterOption{filesystem.WithFilterOptionContentTypes("office-like")}, Entry: entry("docs/report.txt", 10, "text/plain", now), Expect: false},
+			{Name: "Exclude Content Type Wildcard", Options: []filesystem.FilterOption{filesystem.WithFilterOptionExcludeContentTypes("video/*")}, Entry: entry("docs/movie", 10, "video/mp4", now), Expect: false},
+			{Name: "Max Age", Options: []filesystem.FilterOption{filesystem.WithFilterOptionAge(0, time.Hour)}, Entry: entry("docs/report.txt", 10, "", now.Add(-2*time.Hour)), Expect: false},
+			{Name: "Min Age", Options: []filesystem.FilterOption{filesystem.WithFilterOptionAge(time.Hour, 0)}, Entry: entry("docs/report.txt", 10, "", now), Expect: false},
+			{Name: "Within Age", Options: []filesystem.FilterOption{filesystem.WithFilterOptionAge(time.Hour, 3*time.Hour)}, Entry: entry("docs/report.txt", 10, "", now.Add(-2*time.Hour)), Expect: true},
+		}
+		for _, test := range tests {
+			t.Run(test.Name, func(t *testing.T) {
+				assertions := assert.New(t)
+
+				filter, err := filesystem.NewFilter(test.Options...)
+				if !assertions.Nil(err, "failed to prepare filter") {
+					return
+				}
+				assertions.Equal(test.Expect, filter.Match(test.Entry), "invalid match")
+			})
+		}
+	})
+	t.Run("Invalid", func(t *testing.T) {
+		assertions := assert.New(t)
+
+		_, err := filesystem.NewFilter(filesystem.WithFilterOptionExclude("[a-"))
+		assertions.NotNil(err, "invalid globs should fail")
+
+		_, err = filesystem.NewFilter(filesystem.WithFilterOptionIncludeRegex("("))
+		assertions.NotNil(err, "invalid regexes should fail")
+
+		_, err = filesystem.NewFilter(filesystem.WithFilterOptionSize(10, 5))
+		assertions.NotNil(err, "invalid size bounds should fail")
+	})
+	t.Run("Sync", func(t *testing.T) {
+		if os.Getuid() == 0 {
+			t.Skip("Can't run this test as root")
+			return
+		}
+
+		assertions := assert.New(t)
+
+		ctx, cancel := context.WithTimeout(context.TODO(), time.Minute)
+		defer cancel()
+
+		src := directory.New(t.TempDir(), 0o777, 0o777)
+		dst := directory.New(t.TempDir(), 0o777, 0o777)
+
+		modTime := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+		for _, location := range [][]string{{"docs", "report.txt"}, {"docs", "~$report.docx"}} {
+			_, err := src.WriteFile(ctx, location, strings.NewReader("contents"), modTime)
+			if !assertions.Nil(err, "failed to write src file") {
+				return
+			}
+		}
+		kept := []string{"docs", "~$kept.docx"}
+		_, err := dst.WriteFile(ctx, kept, strings.NewReader("contents"), modTime)
+		if !assertions.Nil(err, "failed to write dst file") {
+			return
+		}
+
+		filter, err := filesystem.NewFilter(filesystem.WithFilterOptionExclude("~$*"))
+		if !assertions.Nil(err, "failed to prepare filter") {
+			return
+		}
+
+		result, err := filesystem.Sync(ctx, dst, src,
+			filesystem.WithSyncOptionFilter(filter),
+			filesystem.WithSyncOptionMirror(true),
+			filesystem.WithSyncOptionMaxDeletePercent(100),
+		)
+		if !assertions.Nil(err, "failed to sync files") {
+			return
+		}
+		assertions.Equal(int64(1), result.Copied, "only included files should be copied")
+		assertions.Zero(result.Deleted, "excluded dst files should not be removed")
+
+		_, err = dst.Stat(ctx, []string{"docs", "~$report.docx"})
+		assertions.ErrorIs(err, os.ErrNotExist, "excluded src files should not be copied")
+		_, err = dst.Stat(ctx, kept)
+		assertions.Nil(err, "excluded dst files should be kept")
+	})
+}
@@ -0,0 +1,104 @@
+// Copyright (C) 2025 ZedCloud Org.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package filterfs
+
+import (
+	"context"
+	"io"
+	"iter"
+	"time"
+
+	"github.com/pluto-org-co/fsio/filesystem"
+)
+
+// This FS only lists the files of the underlying Filesystem passing a filter.
+// Every other operation works as normal, so excluded files can still be reached by their location.
+type FilterFS struct {
+	fs     filesystem.Filesystem
+	filter *filesystem.Filter
+}
+
+var (
+	_ filesystem.Filesystem           = (*FilterFS)(nil)
+	_ filesystem.CapabilitiesReporter = (*FilterFS)(nil)
+	_ filesystem.RangeReader          = (*FilterFS)(nil)
+	_ filesystem.Creator              = (*FilterFS)(nil)
+)
+
+func New(fs filesystem.Filesystem, filter *filesystem.Filter) (f *FilterFS) {
+	return &FilterFS{
+		fs:     fs,
+		filter: filter,
+	}
+}
+
+func (f *FilterFS) Capabilities() (caps filesystem.Capability) {
+	return filesystem.Capabilities(f.fs)
+}
+
+func (f *FilterFS) ChecksumTime(ctx context.Context, location []string) (checksum string, err error) {
+	return f.fs.ChecksumTime(ctx, location)
+}
+
+func (f *FilterFS) ChecksumSha256(ctx context.Context, location []string) (checksum string, err error) {
+	return f.fs.ChecksumSha256(ctx, location)
+}
+
+func (f *FilterFS) Stat(ctx context.Context, location []string) (info *filesystem.FileInfo, err error) {
+	return f.fs.Stat(ctx, location)
+}
+
+func (f *FilterFS) Files(ctx context.Context, options ...filesystem.ListOption) (seq iter.Seq2[filesystem.FileEntry, error]) {
+	return func(yield func(filesystem.FileEntry, error) bool) {
+		for entry, err := range f.fs.Files(ctx, options...) {
+			if err != nil {
+				yield(nil, err)
+				return
+			}
+
+			if !f.filter.Match(entry) {
+				continue
+			}
+			if !yield(entry, nil) {
+				return
+			}
+		}
+	}
+}
+
+func (f *FilterFS) Open(ctx context.Context, location []string) (rc io.ReadCloser, err error) {
+	return f.fs.Open(ctx, location)
+}
+
+func (f *FilterFS) OpenRange(ctx context.Context, location []string, offset, length int64) (rc io.ReadCloser, err error) {
+	return filesystem.OpenRange(ctx, f.fs, location, offset, length)
+}
+
+func (f *FilterFS) WriteFile(ctx context.Context, location []string, src io.Reader, modTime time.Time) (finalLocation []string, err error) {
+	return f.fs.WriteFile(ctx, location, src, modTime)
+}
+
+func (f *FilterFS) Create(ctx context.Context, location []string, modTime time.Time) (w filesystem.FileWriter, err error) {
+	return filesystem.Create(ctx, f.fs, location, modTime)
+}
+
+func (f *FilterFS) RemoveAll(ctx context.Context, location []string) (err error) {
+	return f.fs.RemoveAll(ctx, location)
+}
+
+func (f *FilterFS) Move(ctx context.Context, oldLocation, newLocation []string) (finalLocation []string, err error) {
+	return f.fs.Move(ctx, oldLocation, newLocation)
+}
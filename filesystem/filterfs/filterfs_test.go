@@ -0,0 +1,84 @@
+// Copyright (C) 2025 ZedCloud Org.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package filterfs_test
+
+import (
+	"context"
+	"os"
+	"path"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pluto-org-co/fsio/filesystem"
+	"github.com/pluto-org-co/fsio/filesystem/directory"
+	"github.com/pluto-org-co/fsio/filesystem/filterfs"
+	"github.com/pluto-org-co/fsio/filesystem/testsuite"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_FilterFS(t *testing.T) {
+	assertions := assert.New(t)
+
+	tempDir, err := os.MkdirTemp("", "*")
+	if !assertions.Nil(err, "failed to create temp") {
+		return
+	}
+	defer os.RemoveAll(tempDir)
+	localRoot := directory.New(tempDir, 0o777, 0o777)
+
+	// Generated locations never contain these characters
+	filter, err := filesystem.NewFilter(
+		filesystem.WithFilterOptionExclude("~$*", "*.mp4", "Videos"),
+	)
+	if !assertions.Nil(err, "failed to prepare filter") {
+		return
+	}
+	filterRoot := filterfs.New(localRoot, filter)
+
+	t.Run("Testsuite", testsuite.TestFilesystem(t, filterRoot))
+	t.Run("Filtered", func(t *testing.T) {
+		assertions := assert.New(t)
+
+		ctx, cancel := context.WithTimeout(context.TODO(), time.Minute)
+		defer cancel()
+
+		locations := [][]string{
+			{"filtered", "docs", "report.txt"},
+			{"filtered", "docs", "~$report.docx"},
+			{"filtered", "Videos", "holidays.txt"},
+			{"filtered", "movie.mp4"},
+		}
+		for _, location := range locations {
+			_, err := localRoot.WriteFile(ctx, location, strings.NewReader("contents"), time.Now())
+			if !assertions.Nil(err, "failed to write file") {
+				return
+			}
+		}
+
+		var listed []string
+		for entry, err := range filterRoot.Files(ctx, filesystem.WithListOptionPrefix("filtered")) {
+			if !assertions.Nil(err, "failed to list files") {
+				return
+			}
+			listed = append(listed, path.Join(entry.Location()...))
+		}
+		assertions.Equal([]string{"filtered/docs/report.txt"}, listed, "excluded files should not be listed")
+
+		_, err := filterRoot.Stat(ctx, locations[3])
+		assertions.Nil(err, "excluded files should still be reachable")
+	})
+}
@@ -0,0 +1,79 @@
+// Copyright (C) 2025 ZedCloud Org.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package googledrive
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"slices"
+
+	"github.com/pluto-org-co/fsio/filesystem"
+	"google.golang.org/api/googleapi"
+)
+
+// Reasons reported by the Drive API when the request was rejected due to quotas
+var rateLimitReasons = []string{
+	"rateLimitExceeded",
+	"userRateLimitExceeded",
+	"dailyLimitExceeded",
+	"quotaExceeded",
+	"storageQuotaExceeded",
+	"downloadQuotaExceeded",
+	"sharingRateLimitExceeded",
+}
+
+// Reasons reported by the Drive API when the request failed on the server side
+var transientReasons = []string{
+	"backendError",
+	"internalError",
+}
+
+// Wraps the Google API errors with the filesystem errors
+func wrapError(err error) error {
+	var apiErr *googleapi.Error
+	if !errors.As(err, &apiErr) {
+		return err
+	}
+
+	// Already wrapped by a nested call
+	if errors.Is(err, filesystem.ErrRateLimited) || errors.Is(err, filesystem.ErrTransient) ||
+		errors.Is(err, fs.ErrNotExist) || errors.Is(err, fs.ErrPermission) {
+		return err
+	}
+
+	rateLimited := slices.ContainsFunc(apiErr.Errors, func(item googleapi.ErrorItem) bool {
+		return slices.Contains(rateLimitReasons, item.Reason)
+	})
+
+	transient := slices.ContainsFunc(apiErr.Errors, func(item googleapi.ErrorItem) bool {
+		return slices.Contains(transientReasons, item.Reason)
+	})
+
+	switch {
+	case apiErr.Code == http.StatusTooManyRequests || rateLimited:
+		return fmt.Errorf("%w: %w", filesystem.ErrRateLimited, err)
+	case apiErr.Code >= http.StatusInternalServerError || transient:
+		return fmt.Errorf("%w: %w", filesystem.ErrTransient, err)
+	case apiErr.Code == http.StatusNotFound:
+		return fmt.Errorf("%w: %w", fs.ErrNotExist, err)
+	case apiErr.Code == http.StatusForbidden || apiErr.Code == http.StatusUnauthorized:
+		return fmt.Errorf("%w: %w", fs.ErrPermission, err)
+	default:
+		return err
+	}
+}
@@ -20,11 +20,13 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"io/fs"
 	"iter"
-	"log"
 	"net/http"
 	"path"
 	"slices"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/pluto-org-co/fsio/filesystem"
@@ -54,7 +56,11 @@ func New(conf Config) (g *GoogleDrive) {
 	}
 }
 
-var _ filesystem.Filesystem = (*GoogleDrive)(nil)
+var (
+	_ filesystem.Filesystem           = (*GoogleDrive)(nil)
+	_ filesystem.CapabilitiesReporter = (*GoogleDrive)(nil)
+	_ filesystem.RangeReader          = (*GoogleDrive)(nil)
+)
 
 type Config struct {
 	// Constructs the config loader used for preparing the service.
@@ -125,6 +131,13 @@ func (g *GoogleDrive) ClientFromConf(ctx context.Context, conf *jwt.Config) (cli
 }
 
 func (g *GoogleDrive) ChecksumTime(ctx context.Context, location []string) (checksum string, err error) {
+	defer func() { err = wrapError(err) }()
+
+	err = filesystem.ValidateLocation(location)
+	if err != nil {
+		return "", err
+	}
+
 	baseConf := g.jwtLoader()
 	baseClient := g.ClientFromConf(ctx, baseConf)
 
@@ -148,7 +161,11 @@ func (g *GoogleDrive) ChecksumTime(ctx context.Context, location []string) (chec
 		ok, driveName, filename := g.filenameIsCurrentSharedDrives(location)
 		if ok {
 			var driveId string
-			for drive := range shareddrives.SeqDrives(ctx, driveSvc) {
+			for drive, err := range shareddrives.SeqDrives(ctx, driveSvc) {
+				if err != nil {
+					return "", fmt.Errorf("failed to list shared drives: %w", err)
+				}
+
 				if drive.Name == driveName {
 					driveId = drive.Id
 					break
@@ -156,7 +173,7 @@ func (g *GoogleDrive) ChecksumTime(ctx context.Context, location []string) (chec
 			}
 
 			if driveId == "" {
-				return "", fmt.Errorf("drive not found by name: %s", driveName)
+				return "", fmt.Errorf("drive not found by name: %s: %w", driveName, fs.ErrNotExist)
 			}
 
 			checksum, err := shareddrives.ChecksumTime(ctx, driveSvc, driveId, filename)
@@ -186,10 +203,17 @@ func (g *GoogleDrive) ChecksumTime(ctx context.Context, location []string) (chec
 		}
 	}
 
-	return "", fmt.Errorf("file not found: %v", location)
+	return "", fmt.Errorf("file not found: %v: %w", location, fs.ErrNotExist)
 }
 
 func (g *GoogleDrive) ChecksumSha256(ctx context.Context, location []string) (checksum string, err error) {
+	defer func() { err = wrapError(err) }()
+
+	err = filesystem.ValidateLocation(location)
+	if err != nil {
+		return "", err
+	}
+
 	baseConf := g.jwtLoader()
 	baseClient := g.ClientFromConf(ctx, baseConf)
 
@@ -213,7 +237,11 @@ func (g *GoogleDrive) ChecksumSha256(ctx context.Context, location []string) (ch
 		ok, driveName, filename := g.filenameIsCurrentSharedDrives(location)
 		if ok {
 			var driveId string
-			for drive := range shareddrives.SeqDrives(ctx, driveSvc) {
+			for drive, err := range shareddrives.SeqDrives(ctx, driveSvc) {
+				if err != nil {
+					return "", fmt.Errorf("failed to list shared drives: %w", err)
+				}
+
 				if drive.Name == driveName {
 					driveId = drive.Id
 					break
@@ -221,7 +249,7 @@ func (g *GoogleDrive) ChecksumSha256(ctx context.Context, location []string) (ch
 			}
 
 			if driveId == "" {
-				return "", fmt.Errorf("drive not found by name: %s", driveName)
+				return "", fmt.Errorf("drive not found by name: %s: %w", driveName, fs.ErrNotExist)
 			}
 
 			checksum, err := shareddrives.ChecksumSha256(ctx, driveSvc, driveId, filename)
@@ -251,69 +279,247 @@ func (g *GoogleDrive) ChecksumSha256(ctx context.Context, location []string) (ch
 		}
 	}
 
-	return "", fmt.Errorf("file not found: %v", location)
+	return "", fmt.Errorf("file not found: %v: %w", location, fs.ErrNotExist)
+}
+
+func fileInfoFromDrive(location []string, file *drive.File) (info *filesystem.FileInfo) {
+	modTime, _ := time.Parse(time.RFC3339, file.ModifiedTime)
+
+	info = &filesystem.FileInfo{
+		Location:    location,
+		Size:        file.Size,
+		ModTime:     modTime,
+		ContentType: file.MimeType,
+		ETag:        file.Md5Checksum,
+		Version:     strconv.FormatInt(file.Version, 10),
+		ID:          file.Id,
+	}
+
+	// Google Workspace documents are exported on download, their size is only known after the export
+	if strings.HasPrefix(file.MimeType, "application/vnd.google-apps.") {
+		info.Size = -1
+	}
+	return info
+}
+
+func entryFromDrive(location []string, file *drive.File) (entry *filesystem.SimpleFileEntry) {
+	info := fileInfoFromDrive(location, file)
+
+	entry = &filesystem.SimpleFileEntry{
+		LocationValue:    location,
+		ModTimeValue:     info.ModTime,
+		SizeValue:        info.Size,
+		ContentTypeValue: info.ContentType,
+		SysValue:         file,
+	}
+	return entry
+}
+
+func (g *GoogleDrive) Stat(ctx context.Context, location []string) (info *filesystem.FileInfo, err error) {
+	defer func() { err = wrapError(err) }()
+
+	err = filesystem.ValidateLocation(location)
+	if err != nil {
+		return nil, err
+	}
+
+	baseConf := g.jwtLoader()
+	baseClient := g.ClientFromConf(ctx, baseConf)
+
+	driveSvc, err := drive.NewService(ctx, option.WithHTTPClient(baseClient))
+	if err != nil {
+		return nil, fmt.Errorf("failed to prepare drive service: %w", err)
+	}
+
+	if g.currentAccount {
+		ok, filename := g.filenameIsCurrentUser(location)
+		if ok {
+			file, err := drives.Stat(ctx, driveSvc, filename)
+			if err != nil {
+				return nil, fmt.Errorf("failed to get current user file information: %w", err)
+			}
+			return fileInfoFromDrive(location, file), nil
+		}
+	}
+
+	if g.sharedDrives {
+		ok, driveName, filename := g.filenameIsCurrentSharedDrives(location)
+		if ok {
+			var driveId string
+			for drive, err := range shareddrives.SeqDrives(ctx, driveSvc) {
+				if err != nil {
+					return nil, fmt.Errorf("failed to list shared drives: %w", err)
+				}
+
+				if drive.Name == driveName {
+					driveId = drive.Id
+					break
+				}
+			}
+
+			if driveId == "" {
+				return nil, fmt.Errorf("drive not found by name: %s: %w", driveName, fs.ErrNotExist)
+			}
+
+			file, err := shareddrives.Stat(ctx, driveSvc, driveId, filename)
+			if err != nil {
+				return nil, fmt.Errorf("failed to get shared drive file information: %w", err)
+			}
+			return fileInfoFromDrive(location, file), nil
+		}
+	}
+
+	if g.otherUsers {
+		ok, _, username, filename := g.filenameIsUserAccountDrive(location)
+		if ok {
+			userConf := g.jwtLoader()
+			userConf.Subject = username
+
+			userSvc, err := drive.NewService(ctx, option.WithHTTPClient(g.ClientFromConf(ctx, userConf)))
+			if err != nil {
+				return nil, fmt.Errorf("failed to prepare client for user: %w", err)
+			}
+
+			file, err := drives.Stat(ctx, userSvc, filename)
+			if err != nil {
+				return nil, fmt.Errorf("failed to get user file information: %w", err)
+			}
+			return fileInfoFromDrive(location, file), nil
+		}
+	}
+
+	return nil, fmt.Errorf("file not found: %v: %w", location, fs.ErrNotExist)
 }
 
-func (g *GoogleDrive) Files(ctx context.Context) (seq iter.Seq[filesystem.FileEntry]) {
+// Checks if the listing prefix overlaps with the namespace (the virtual directories preceding the actual drive files).
+// When it does, folder corresponds to the part of the prefix that should be resolved inside the drive.
+func matchNamespace(prefix, namespace []string, recursive bool) (ok bool, folder []string) {
+	if len(prefix) < len(namespace) {
+		if !recursive {
+			// There are no files at the namespace levels
+			return false, nil
+		}
+		return slices.Equal(prefix, namespace[:len(prefix)]), nil
+	}
+
+	if !slices.Equal(prefix[:len(namespace)], namespace) {
+		return false, nil
+	}
+	return true, prefix[len(namespace):]
+}
+
+func (g *GoogleDrive) Files(ctx context.Context, options ...filesystem.ListOption) (seq iter.Seq2[filesystem.FileEntry, error]) {
+	listCtx := filesystem.NewListCtx(options...)
+
+	err := filesystem.ValidatePrefix(listCtx.Prefix)
+	if err != nil {
+		return func(yield func(filesystem.FileEntry, error) bool) {
+			yield(nil, err)
+		}
+	}
+
 	baseConf := g.jwtLoader()
 	baseClient := g.ClientFromConf(ctx, baseConf)
 
 	driveSvc, err := drive.NewService(ctx, option.WithHTTPClient(baseClient))
 	if err != nil {
-		log.Printf("failed to get drive service: %v", err)
-		return func(yield func(filesystem.FileEntry) bool) {}
+		return func(yield func(filesystem.FileEntry, error) bool) {
+			yield(nil, fmt.Errorf("failed to get drive service: %w", err))
+		}
 	}
 
-	adminSvc, _ := admin.NewService(ctx, option.WithHTTPClient(baseClient))
+	adminSvc, adminErr := admin.NewService(ctx, option.WithHTTPClient(baseClient))
+
+	return func(yield func(filesystem.FileEntry, error) bool) {
+		fail := func(err error) {
+			yield(nil, wrapError(err))
+		}
 
-	return func(yield func(filesystem.FileEntry) bool) {
 		// Start with the files owned by this account.
-		if g.currentAccount {
-			for location, file := range drives.SeqFiles(ctx, driveSvc) {
-				modTime, _ := time.Parse(time.RFC3339, file.ModifiedTime)
-				entry := &filesystem.SimpleFileEntry{
-					LocationValue: g.currentUserFilename(location),
-					ModTimeValue:  modTime,
+		if ok, folder := matchNamespace(listCtx.Prefix, g.currentUserFilename(nil), listCtx.Recursive); g.currentAccount && ok {
+			for entry, err := range drives.SeqFilesAt(ctx, driveSvc, folder, listCtx.Recursive) {
+				if err != nil {
+					fail(fmt.Errorf("failed to list personal files: %w", err))
+					return
 				}
-				if !yield(entry) {
+
+				fileEntry := entryFromDrive(g.currentUserFilename(entry.Location), entry.File)
+				if !yield(fileEntry, nil) {
 					return
 				}
 			}
 		}
 
 		if g.sharedDrives {
-			for drive := range shareddrives.SeqDrives(ctx, driveSvc) {
-				for location, file := range shareddrives.SeqFiles(ctx, driveSvc, drive.Id) {
-					modTime, _ := time.Parse(time.RFC3339, file.ModifiedTime)
-					entry := &filesystem.SimpleFileEntry{
-						LocationValue: g.currentSharedDriveFilename(drive.Name, location),
-						ModTimeValue:  modTime,
+			for drive, err := range shareddrives.SeqDrives(ctx, driveSvc) {
+				if err != nil {
+					fail(fmt.Errorf("failed to list shared drives: %w", err))
+					return
+				}
+
+				ok, folder := matchNamespace(listCtx.Prefix, g.currentSharedDriveFilename(drive.Name, nil), listCtx.Recursive)
+				if !ok {
+					continue
+				}
+
+				for entry, err := range shareddrives.SeqFilesAt(ctx, driveSvc, drive.Id, folder, listCtx.Recursive) {
+					if err != nil {
+						fail(fmt.Errorf("failed to list shared drive files: %s: %w", drive.Name, err))
+						return
 					}
-					if !yield(entry) {
+
+					fileEntry := entryFromDrive(g.currentSharedDriveFilename(drive.Name, entry.Location), entry.File)
+					if !yield(fileEntry, nil) {
 						return
 					}
 				}
 			}
 		}
 
-		if g.otherUsers && adminSvc != nil {
-			for domain := range directory.SeqDomains(ctx, adminSvc) {
-				for user := range directory.SeqUsers(ctx, adminSvc, domain.DomainName) {
+		if g.otherUsers {
+			if adminErr != nil {
+				fail(fmt.Errorf("failed to get admin service: %w", adminErr))
+				return
+			}
+
+			for domain, err := range directory.SeqDomains(ctx, adminSvc) {
+				if err != nil {
+					fail(fmt.Errorf("failed to list domains: %w", err))
+					return
+				}
+
+				// Avoid listing the users of domains outside the prefix
+				if ok, _ := matchNamespace(listCtx.Prefix, []string{"domains", domain.DomainName}, true); !ok {
+					continue
+				}
+
+				for user, err := range directory.SeqUsers(ctx, adminSvc, domain.DomainName) {
+					if err != nil {
+						fail(fmt.Errorf("failed to list users: %s: %w", domain.DomainName, err))
+						return
+					}
+
+					ok, folder := matchNamespace(listCtx.Prefix, g.userAccountDriveFilename(domain.DomainName, user.PrimaryEmail, nil), listCtx.Recursive)
+					if !ok {
+						continue
+					}
+
 					userConf := g.jwtLoader()
 					userConf.Subject = user.PrimaryEmail
 
 					userSvc, err := drive.NewService(ctx, option.WithHTTPClient(g.ClientFromConf(ctx, userConf)))
 					if err != nil {
-						log.Printf("failed to load user configuration: %v", err)
+						fail(fmt.Errorf("failed to load user configuration: %w", err))
 						return
 					}
-					for location, file := range drives.SeqFiles(ctx, userSvc) {
-						modTime, _ := time.Parse(time.RFC3339, file.ModifiedTime)
-						entry := &filesystem.SimpleFileEntry{
-							LocationValue: g.userAccountDriveFilename(domain.DomainName, user.PrimaryEmail, location),
-							ModTimeValue:  modTime,
+					for entry, err := range drives.SeqFilesAt(ctx, userSvc, folder, listCtx.Recursive) {
+						if err != nil {
+							fail(fmt.Errorf("failed to list user files: %s: %w", user.PrimaryEmail, err))
+							return
 						}
-						if !yield(entry) {
+
+						fileEntry := entryFromDrive(g.userAccountDriveFilename(domain.DomainName, user.PrimaryEmail, entry.Location), entry.File)
+						if !yield(fileEntry, nil) {
 							return
 						}
 					}
@@ -324,6 +530,13 @@ func (g *GoogleDrive) Files(ctx context.Context) (seq iter.Seq[filesystem.FileEn
 }
 
 func (g *GoogleDrive) Open(ctx context.Context, location []string) (rc io.ReadCloser, err error) {
+	defer func() { err = wrapError(err) }()
+
+	err = filesystem.ValidateLocation(location)
+	if err != nil {
+		return nil, err
+	}
+
 	driveSvc, err := drive.NewService(ctx, option.WithHTTPClient(g.ClientFromConf(ctx, g.jwtLoader())))
 	if err != nil {
 		return nil, fmt.Errorf("failed to create drive service: %w", err)
@@ -344,14 +557,18 @@ func (g *GoogleDrive) Open(ctx context.Context, location []string) (rc io.ReadCl
 		ok, drivename, filename := g.filenameIsCurrentSharedDrives(location)
 		if ok {
 			var driveId string
-			for driveEntry := range shareddrives.SeqDrives(ctx, driveSvc) {
+			for driveEntry, err := range shareddrives.SeqDrives(ctx, driveSvc) {
+				if err != nil {
+					return nil, fmt.Errorf("failed to list shared drives: %w", err)
+				}
+
 				if driveEntry.Name == drivename {
 					driveId = driveEntry.Id
 					break
 				}
 			}
 			if driveId == "" {
-				return nil, fmt.Errorf("failed to find drive by its name: %w", err)
+				return nil, fmt.Errorf("failed to find drive by its name: %s: %w", drivename, fs.ErrNotExist)
 			}
 
 			rc, err := shareddrives.Open(ctx, driveSvc, driveId, filename)
@@ -380,17 +597,195 @@ func (g *GoogleDrive) Open(ctx context.Context, location []string) (rc io.ReadCl
 			return rc, nil
 		}
 	}
-	return nil, fmt.Errorf("file not found: %s", path.Join(location...))
+	return nil, fmt.Errorf("file not found: %s: %w", path.Join(location...), fs.ErrNotExist)
+}
+
+func (g *GoogleDrive) OpenRange(ctx context.Context, location []string, offset, length int64) (rc io.ReadCloser, err error) {
+	defer func() { err = wrapError(err) }()
+
+	if offset < 0 {
+		return nil, fmt.Errorf("invalid offset: %d: %w", offset, fs.ErrInvalid)
+	}
+
+	err = filesystem.ValidateLocation(location)
+	if err != nil {
+		return nil, err
+	}
+
+	rc, err = g.openRange(ctx, location, offset, length)
+	if errors.Is(err, errors.ErrUnsupported) {
+		// Google Workspace documents can only be exported as a whole
+		return filesystem.OpenRangeFallback(ctx, g, location, offset, length)
+	}
+	return rc, err
+}
+
+func (g *GoogleDrive) openRange(ctx context.Context, location []string, offset, length int64) (rc io.ReadCloser, err error) {
+	driveSvc, err := drive.NewService(ctx, option.WithHTTPClient(g.ClientFromConf(ctx, g.jwtLoader())))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create drive service: %w", err)
+	}
+
+	if g.currentAccount {
+		ok, filename := g.filenameIsCurrentUser(location)
+		if ok {
+			rc, err := drives.OpenRange(ctx, driveSvc, filename, offset, length)
+			if err != nil {
+				return nil, fmt.Errorf("failed to open current user file: %w", err)
+			}
+			return rc, nil
+		}
+	}
+
+	if g.sharedDrives {
+		ok, drivename, filename := g.filenameIsCurrentSharedDrives(location)
+		if ok {
+			var driveId string
+			for driveEntry, err := range shareddrives.SeqDrives(ctx, driveSvc) {
+				if err != nil {
+					return nil, fmt.Errorf("failed to list shared drives: %w", err)
+				}
+
+				if driveEntry.Name == drivename {
+					driveId = driveEntry.Id
+					break
+				}
+			}
+			if driveId == "" {
+				return nil, fmt.Errorf("failed to find drive by its name: %s: %w", drivename, fs.ErrNotExist)
+			}
+
+			rc, err := shareddrives.OpenRange(ctx, driveSvc, driveId, filename, offset, length)
+			if err != nil {
+				return nil, fmt.Errorf("failed to open drive file: %w", err)
+			}
+			return rc, nil
+		}
+	}
+
+	if g.otherUsers {
+		ok, _, username, filename := g.filenameIsUserAccountDrive(location)
+		if ok {
+			baseConf := g.jwtLoader()
+			baseConf.Subject = username
+
+			driveSvc, err := drive.NewService(ctx, option.WithHTTPClient(g.ClientFromConf(ctx, baseConf)))
+			if err != nil {
+				return nil, fmt.Errorf("failed to create drive service: %w", err)
+			}
+
+			rc, err := drives.OpenRange(ctx, driveSvc, filename, offset, length)
+			if err != nil {
+				return nil, fmt.Errorf("failed to open user file: %s: %w", username, err)
+			}
+			return rc, nil
+		}
+	}
+	return nil, fmt.Errorf("file not found: %s: %w", path.Join(location...), fs.ErrNotExist)
+}
+
+// Google Drive is exposed as a read-only filesystem whose files can be trashed
+func (g *GoogleDrive) Capabilities() (caps filesystem.Capability) {
+	return filesystem.CapabilityRead | filesystem.CapabilityRangeRead | filesystem.CapabilityRemove
 }
 
 func (g *GoogleDrive) WriteFile(ctx context.Context, location []string, src io.Reader, modTime time.Time) (finalLocation []string, err error) {
-	return nil, errors.New("operation not supported")
+	return nil, fmt.Errorf("failed to write file: %w", filesystem.ErrUnsupported)
 }
 
+// Moves the file or folder to the trash of its drive, so it can still be restored from Google Drive.
+// Missing files are ignored. Entire drives can't be removed
 func (g *GoogleDrive) RemoveAll(ctx context.Context, location []string) (err error) {
-	return errors.New("operation not supported")
+	defer func() { err = wrapError(err) }()
+
+	err = filesystem.ValidateLocation(location)
+	if err != nil {
+		return err
+	}
+
+	err = g.trash(ctx, location)
+	if errors.Is(wrapError(err), fs.ErrNotExist) {
+		return nil
+	}
+	return err
+}
+
+func (g *GoogleDrive) trash(ctx context.Context, location []string) (err error) {
+	driveSvc, err := drive.NewService(ctx, option.WithHTTPClient(g.ClientFromConf(ctx, g.jwtLoader())))
+	if err != nil {
+		return fmt.Errorf("failed to create drive service: %w", err)
+	}
+
+	if g.currentAccount {
+		ok, filename := g.filenameIsCurrentUser(location)
+		if ok {
+			if len(filename) == 0 {
+				return fmt.Errorf("can't remove the current user drive: %w", filesystem.ErrUnsupported)
+			}
+
+			err = drives.Trash(ctx, driveSvc, filename)
+			if err != nil {
+				return fmt.Errorf("failed to trash current user file: %w", err)
+			}
+			return nil
+		}
+	}
+
+	if g.sharedDrives {
+		ok, drivename, filename := g.filenameIsCurrentSharedDrives(location)
+		if ok {
+			if len(filename) == 0 {
+				return fmt.Errorf("can't remove shared drive: %s: %w", drivename, filesystem.ErrUnsupported)
+			}
+
+			var driveId string
+			for driveEntry, err := range shareddrives.SeqDrives(ctx, driveSvc) {
+				if err != nil {
+					return fmt.Errorf("failed to list shared drives: %w", err)
+				}
+
+				if driveEntry.Name == drivename {
+					driveId = driveEntry.Id
+					break
+				}
+			}
+			if driveId == "" {
+				return fmt.Errorf("failed to find drive by its name: %s: %w", drivename, fs.ErrNotExist)
+			}
+
+			err = shareddrives.Trash(ctx, driveSvc, driveId, filename)
+			if err != nil {
+				return fmt.Errorf("failed to trash drive file: %w", err)
+			}
+			return nil
+		}
+	}
+
+	if g.otherUsers {
+		ok, _, username, filename := g.filenameIsUserAccountDrive(location)
+		if ok {
+			if len(filename) == 0 {
+				return fmt.Errorf("can't remove user drive: %s: %w", username, filesystem.ErrUnsupported)
+			}
+
+			baseConf := g.jwtLoader()
+			baseConf.Subject = username
+
+			driveSvc, err := drive.NewService(ctx, option.WithHTTPClient(g.ClientFromConf(ctx, baseConf)))
+			if err != nil {
+				return fmt.Errorf("failed to create drive service: %w", err)
+			}
+
+			err = drives.Trash(ctx, driveSvc, filename)
+			if err != nil {
+				return fmt.Errorf("failed to trash user file: %s: %w", username, err)
+			}
+			return nil
+		}
+	}
+	return fmt.Errorf("file not found: %s: %w", path.Join(location...), fs.ErrNotExist)
 }
 
 func (g *GoogleDrive) Move(ctx context.Context, oldLocation, newLocation []string) (finalLocation []string, err error) {
-	return nil, errors.New("operation not supported")
+	return nil, fmt.Errorf("failed to move file: %w", filesystem.ErrUnsupported)
 }
@@ -64,7 +64,11 @@ func Test_GoogleDrive(t *testing.T) {
 				defer cancel()
 
 				var index int
-				for entry := range gd.Files(ctx) {
+				for entry, err := range gd.Files(ctx) {
+					if !assertions.Nil(err, "failed to list files") {
+						return
+					}
+
 					t.Logf("[%d] Filename: %s", index, entry)
 					index++
 					if index >= 5 {
@@ -53,7 +53,16 @@ func New(level int, fs filesystem.Filesystem) (g *Gzip) {
 	}
 }
 
-var _ filesystem.Filesystem = (*Gzip)(nil)
+var (
+	_ filesystem.Filesystem           = (*Gzip)(nil)
+	_ filesystem.CapabilitiesReporter = (*Gzip)(nil)
+	_ filesystem.RangeReader          = (*Gzip)(nil)
+)
+
+// Same capabilities of the underlying filesystem. Compressed contents can't be read by ranges
+func (g *Gzip) Capabilities() (caps filesystem.Capability) {
+	return filesystem.Capabilities(g.fs) &^ filesystem.CapabilityRangeRead
+}
 
 func (g *Gzip) ChecksumTime(ctx context.Context, location []string) (checksum string, err error) {
 	return g.fs.ChecksumTime(ctx, location)
@@ -73,8 +82,43 @@ func (g *Gzip) ChecksumSha256(ctx context.Context, location []string) (checksum
 	return checksum, nil
 }
 
-func (g *Gzip) Files(ctx context.Context) (seq iter.Seq[filesystem.FileEntry]) {
-	return g.fs.Files(ctx)
+func (g *Gzip) Stat(ctx context.Context, location []string) (info *filesystem.FileInfo, err error) {
+	info, err = g.fs.Stat(ctx, location)
+	if err != nil {
+		return nil, err
+	}
+
+	// Compressed files only know their size and content type after decompression
+	if info.ContentType == "" || info.ContentType == "application/gzip" {
+		info.Size = -1
+		info.ContentType = ""
+	}
+	return info, nil
+}
+
+// Files may be stored compressed, in that case their size and content type are only known after decompression
+func (g *Gzip) Files(ctx context.Context, options ...filesystem.ListOption) (seq iter.Seq2[filesystem.FileEntry, error]) {
+	return func(yield func(filesystem.FileEntry, error) bool) {
+		for entry, err := range g.fs.Files(ctx, options...) {
+			if err != nil {
+				yield(nil, err)
+				return
+			}
+
+			if entry.ContentType() == "" || entry.ContentType() == "application/gzip" {
+				entry = &filesystem.SimpleFileEntry{
+					LocationValue: entry.Location(),
+					ModTimeValue:  entry.ModTime(),
+					SizeValue:     -1,
+					SysValue:      entry.Sys(),
+				}
+			}
+
+			if !yield(entry, nil) {
+				return
+			}
+		}
+	}
 }
 
 type gzipReader struct {
@@ -133,6 +177,12 @@ func (g *Gzip) Open(ctx context.Context, location []string) (rc io.ReadCloser, e
 	return rc, nil
 }
 
+// Offsets refer to the decompressed contents, so the underlying ranged reads can't be used.
+// The file is decompressed and the first offset bytes discarded
+func (g *Gzip) OpenRange(ctx context.Context, location []string, offset, length int64) (rc io.ReadCloser, err error) {
+	return filesystem.OpenRangeFallback(ctx, g, location, offset, length)
+}
+
 func (g *Gzip) WriteFile(ctx context.Context, location []string, src io.Reader, modTime time.Time) (finalLocation []string, err error) {
 	rawFile, err := os.CreateTemp("", "*")
 	if err != nil {
@@ -194,11 +244,12 @@ func (g *Gzip) WriteFile(ctx context.Context, location []string, src io.Reader,
 		return nil, fmt.Errorf("failed to get compressed file info: %w", err)
 	}
 
+	// Streamed through Create, so the underlying filesystem doesn't buffer the spooled contents again
 	if compressedInfo.Size() < rawInfo.Size() {
-		return g.fs.WriteFile(ctx, location, compressedFile, modTime)
+		return filesystem.CreateFrom(ctx, g.fs, location, compressedFile, modTime)
 	}
 
-	return g.fs.WriteFile(ctx, location, rawFile, modTime)
+	return filesystem.CreateFrom(ctx, g.fs, location, rawFile, modTime)
 }
 
 func (g *Gzip) RemoveAll(ctx context.Context, location []string) (err error) {
@@ -0,0 +1,257 @@
+// Copyright (C) 2025 ZedCloud Org.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package journal
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"sync"
+
+	"github.com/pluto-org-co/fsio/filesystem"
+)
+
+// Returned when recording changes into a journal opened with Load
+var ErrReadOnly = errors.New("read-only journal")
+
+// Line of the journal file
+type entry struct {
+	Location []string `json:"location"`
+	filesystem.StateRecord
+	Deleted bool `json:"deleted,omitempty"`
+}
+
+// Sync state stored as an append-only file of JSON lines, one per recorded change.
+// Every change is written as soon as it is recorded, so interrupted syncs resume from the last synchronized file.
+// The file is compacted when opened once superseded lines outnumber the live records.
+type Journal struct {
+	mutex    sync.Mutex
+	filename string
+	file     *os.File
+	encoder  *json.Encoder
+	records  map[string]entry
+	// Lines written to the file
+	lines int
+}
+
+var _ filesystem.SyncState = (*Journal)(nil)
+
+// Opens the journal file, creating it when missing
+func Open(filename string) (j *Journal, err error) {
+	j = &Journal{
+		filename: filename,
+		records:  map[string]entry{},
+	}
+
+	err = j.load()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load journal: %w", err)
+	}
+
+	if j.lines > 2*len(j.records) {
+		err = j.compact()
+		if err != nil {
+			return nil, fmt.Errorf("failed to compact journal: %w", err)
+		}
+	}
+
+	file, err := os.OpenFile(filename, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open journal: %w", err)
+	}
+	j.file = file
+	j.encoder = json.NewEncoder(file)
+	return j, nil
+}
+
+// Loads the journal without creating nor compacting its file, for consulting the state without
+// modifying it, like during dry runs. Recording changes fails with ErrReadOnly
+func Load(filename string) (j *Journal, err error) {
+	j = &Journal{
+		filename: filename,
+		records:  map[string]entry{},
+	}
+
+	err = j.load()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load journal: %w", err)
+	}
+	return j, nil
+}
+
+func (j *Journal) load() (err error) {
+	file, err := os.Open(j.filename)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil
+		}
+		return fmt.Errorf("failed to open file: %w", err)
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(nil, 1024*1024)
+	for scanner.Scan() {
+		j.lines++
+
+		// Lines cut by an interrupted write are ignored, their file is synchronized again
+		var line entry
+		err = json.Unmarshal(scanner.Bytes(), &line)
+		if err != nil {
+			continue
+		}
+
+		key := path.Join(line.Location...)
+		if line.Deleted {
+			delete(j.records, key)
+			continue
+		}
+		j.records[key] = line
+	}
+	err = scanner.Err()
+	if err != nil {
+		return fmt.Errorf("failed to read file: %w", err)
+	}
+	return nil
+}
+
+// Rewrites the file with the live records only
+func (j *Journal) compact() (err error) {
+	temp, err := os.CreateTemp(filepath.Dir(j.filename), filepath.Base(j.filename)+".*")
+	if err != nil {
+		return fmt.Errorf("failed to create temp file: %w", err)
+	}
+	defer os.Remove(temp.Name())
+	defer temp.Close()
+
+	writer := bufio.NewWriter(temp)
+	encoder := json.NewEncoder(writer)
+	for _, line := range j.records {
+		err = encoder.Encode(line)
+		if err != nil {
+			return fmt.Errorf("failed to write record: %w", err)
+		}
+	}
+	err = writer.Flush()
+	if err != nil {
+		return fmt.Errorf("failed to flush records: %w", err)
+	}
+	err = temp.Sync()
+	if err != nil {
+		return fmt.Errorf("failed to sync temp file: %w", err)
+	}
+	err = temp.Close()
+	if err != nil {
+		return fmt.Errorf("failed to close temp file: %w", err)
+	}
+
+	err = os.Rename(temp.Name(), j.filename)
+	if err != nil {
+		return fmt.Errorf("failed to replace journal: %w", err)
+	}
+	j.lines = len(j.records)
+	return nil
+}
+
+func (j *Journal) Get(location []string) (record filesystem.StateRecord, found bool) {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+
+	line, found := j.records[path.Join(location...)]
+	return line.StateRecord, found
+}
+
+// Records are only written when they changed
+func (j *Journal) Put(location []string, record filesystem.StateRecord) (err error) {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+
+	if j.encoder == nil {
+		return ErrReadOnly
+	}
+
+	key := path.Join(location...)
+	current, found := j.records[key]
+	if found && current.ModTime.Equal(record.ModTime) && current.Size == record.Size {
+		return nil
+	}
+
+	line := entry{Location: slices.Clone(location), StateRecord: record}
+	err = j.encoder.Encode(line)
+	if err != nil {
+		return fmt.Errorf("failed to write record: %w", err)
+	}
+	j.records[key] = line
+	j.lines++
+	return nil
+}
+
+func (j *Journal) Delete(location []string) (err error) {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+
+	key := path.Join(location...)
+	_, found := j.records[key]
+	if !found {
+		return nil
+	}
+	if j.encoder == nil {
+		return ErrReadOnly
+	}
+
+	err = j.encoder.Encode(entry{Location: location, Deleted: true})
+	if err != nil {
+		return fmt.Errorf("failed to write record: %w", err)
+	}
+	delete(j.records, key)
+	j.lines++
+	return nil
+}
+
+func (j *Journal) Locations() (locations [][]string) {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+
+	locations = make([][]string, 0, len(j.records))
+	for _, line := range j.records {
+		locations = append(locations, slices.Clone(line.Location))
+	}
+	return locations
+}
+
+// Number of recorded files
+func (j *Journal) Len() (n int) {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+
+	return len(j.records)
+}
+
+func (j *Journal) Close() (err error) {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+
+	if j.file == nil {
+		return nil
+	}
+	return j.file.Close()
+}
@@ -0,0 +1,167 @@
+// Copyright (C) 2025 ZedCloud Org.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package journal_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pluto-org-co/fsio/filesystem"
+	"github.com/pluto-org-co/fsio/filesystem/journal"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Journal(t *testing.T) {
+	modTime := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	record := filesystem.StateRecord{ModTime: modTime, Size: 10}
+
+	t.Run("Persist", func(t *testing.T) {
+		assertions := assert.New(t)
+
+		filename := filepath.Join(t.TempDir(), "journal")
+		j, err := journal.Open(filename)
+		if !assertions.Nil(err, "failed to open journal") {
+			return
+		}
+
+		assertions.Nil(j.Put([]string{"docs", "kept.txt"}, record), "failed to put record")
+		assertions.Nil(j.Put([]string{"docs", "removed.txt"}, record), "failed to put record")
+		assertions.Nil(j.Delete([]string{"docs", "removed.txt"}), "failed to delete record")
+		assertions.Nil(j.Close(), "failed to close journal")
+
+		j, err = journal.Open(filename)
+		if !assertions.Nil(err, "failed to reopen journal") {
+			return
+		}
+		defer j.Close()
+
+		assertions.Equal(1, j.Len(), "only live records should be loaded")
+		assertions.Equal([][]string{{"docs", "kept.txt"}}, j.Locations(), "only live locations should be listed")
+		found, ok := j.Get([]string{"docs", "kept.txt"})
+		assertions.True(ok, "record should persist")
+		assertions.True(found.ModTime.Equal(modTime), "mod time should persist")
+		assertions.Equal(record.Size, found.Size, "size should persist")
+
+		_, ok = j.Get([]string{"docs", "removed.txt"})
+		assertions.False(ok, "deleted record should not persist")
+	})
+	t.Run("Interrupted", func(t *testing.T) {
+		assertions := assert.New(t)
+
+		filename := filepath.Join(t.TempDir(), "journal")
+		j, err := journal.Open(filename)
+		if !assertions.Nil(err, "failed to open journal") {
+			return
+		}
+		assertions.Nil(j.Put([]string{"docs", "kept.txt"}, record), "failed to put record")
+		assertions.Nil(j.Close(), "failed to close journal")
+
+		file, err := os.OpenFile(filename, os.O_WRONLY|os.O_APPEND, 0)
+		if !assertions.Nil(err, "failed to open journal file") {
+			return
+		}
+		_, err = file.WriteString(`{"location":["docs","cut`)
+		file.Close()
+		if !assertions.Nil(err, "failed to write cut line") {
+			return
+		}
+
+		j, err = journal.Open(filename)
+		if !assertions.Nil(err, "cut lines should be ignored") {
+			return
+		}
+		defer j.Close()
+		assertions.Equal(1, j.Len(), "previous records should be loaded")
+	})
+	t.Run("Compact", func(t *testing.T) {
+		assertions := assert.New(t)
+
+		filename := filepath.Join(t.TempDir(), "journal")
+		j, err := journal.Open(filename)
+		if !assertions.Nil(err, "failed to open journal") {
+			return
+		}
+		for index := range 10 {
+			changed := record
+			changed.Size = int64(index)
+			assertions.Nil(j.Put([]string{"docs", "changed.txt"}, changed), "failed to put record")
+		}
+		assertions.Nil(j.Close(), "failed to close journal")
+
+		j, err = journal.Open(filename)
+		if !assertions.Nil(err, "failed to reopen journal") {
+			return
+		}
+		defer j.Close()
+
+		contents, err := os.ReadFile(filename)
+		if !assertions.Nil(err, "failed to read journal") {
+			return
+		}
+		assertions.Equal(1, strings.Count(string(contents), "\n"), "superseded lines should be removed")
+
+		found, _ := j.Get([]string{"docs", "changed.txt"})
+		assertions.Equal(int64(9), found.Size, "last record should be kept")
+	})
+	t.Run("ReadOnly", func(t *testing.T) {
+		assertions := assert.New(t)
+
+		filename := filepath.Join(t.TempDir(), "journal")
+		j, err := journal.Load(filename)
+		if !assertions.Nil(err, "failed to load missing journal") {
+			return
+		}
+		assertions.Nil(j.Close(), "failed to close journal")
+		_, err = os.Stat(filename)
+		assertions.ErrorIs(err, os.ErrNotExist, "loading should not create the file")
+
+		j, err = journal.Open(filename)
+		if !assertions.Nil(err, "failed to open journal") {
+			return
+		}
+		for index := range 10 {
+			changed := record
+			changed.Size = int64(index)
+			assertions.Nil(j.Put([]string{"docs", "changed.txt"}, changed), "failed to put record")
+		}
+		assertions.Nil(j.Close(), "failed to close journal")
+
+		before, err := os.ReadFile(filename)
+		if !assertions.Nil(err, "failed to read journal") {
+			return
+		}
+
+		j, err = journal.Load(filename)
+		if !assertions.Nil(err, "failed to load journal") {
+			return
+		}
+		defer j.Close()
+
+		found, _ := j.Get([]string{"docs", "changed.txt"})
+		assertions.Equal(int64(9), found.Size, "records should be loaded")
+		assertions.ErrorIs(j.Put([]string{"docs", "other.txt"}, record), journal.ErrReadOnly, "recording should fail")
+		assertions.ErrorIs(j.Delete([]string{"docs", "changed.txt"}), journal.ErrReadOnly, "recording should fail")
+
+		after, err := os.ReadFile(filename)
+		if !assertions.Nil(err, "failed to read journal") {
+			return
+		}
+		assertions.Equal(before, after, "loading should not compact the file")
+	})
+}
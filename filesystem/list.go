@@ -0,0 +1,63 @@
+// Copyright (C) 2025 ZedCloud Org.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package filesystem
+
+import (
+	"slices"
+)
+
+type ListCtx struct {
+	// Only the files under this location are listed
+	Prefix []string
+	// When false only the files directly inside the prefix are listed
+	Recursive bool
+}
+
+type ListOption func(ctx *ListCtx) (err error)
+
+func WithListOptionPrefix(prefix ...string) (option ListOption) {
+	return func(ctx *ListCtx) (err error) {
+		ctx.Prefix = prefix
+		return nil
+	}
+}
+
+func WithListOptionRecursive(recursive bool) (option ListOption) {
+	return func(ctx *ListCtx) (err error) {
+		ctx.Recursive = recursive
+		return nil
+	}
+}
+
+// Prepares the listing context from the passed options.
+// By default the entire filesystem is listed recursively
+func NewListCtx(options ...ListOption) (listCtx *ListCtx) {
+	listCtx = &ListCtx{
+		Recursive: true,
+	}
+	for _, option := range options {
+		option(listCtx)
+	}
+	return listCtx
+}
+
+// Reports if the location should be part of the listing
+func (l *ListCtx) Match(location []string) (ok bool) {
+	if len(location) <= len(l.Prefix) || !slices.Equal(location[:len(l.Prefix)], l.Prefix) {
+		return false
+	}
+	return l.Recursive || len(location) == len(l.Prefix)+1
+}
@@ -0,0 +1,56 @@
+// Copyright (C) 2025 ZedCloud Org.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package filesystem
+
+import (
+	"fmt"
+	"io/fs"
+	"path"
+	"strings"
+)
+
+// Returned by filesystems when the location can't be safely mapped into the backend.
+// It wraps fs.ErrInvalid so both sentinels can be used with errors.Is
+var ErrInvalidLocation = fmt.Errorf("invalid location: %w", fs.ErrInvalid)
+
+// Verifies the location only refers to a file inside the filesystem.
+// Rejects empty locations, empty, "." and ".." segments, NUL bytes and absolute segments.
+// Segments containing "/" are accepted as long as each of their parts is valid
+func ValidateLocation(location []string) (err error) {
+	if len(location) == 0 {
+		return fmt.Errorf("%w: empty location", ErrInvalidLocation)
+	}
+	return ValidatePrefix(location)
+}
+
+// Same as ValidateLocation but the empty location, referring to the root, is accepted
+func ValidatePrefix(prefix []string) (err error) {
+	for _, segment := range prefix {
+		if strings.ContainsRune(segment, 0) {
+			return fmt.Errorf("%w: NUL byte in segment: %q", ErrInvalidLocation, segment)
+		}
+
+		for part := range strings.SplitSeq(segment, "/") {
+			switch part {
+			case "":
+				return fmt.Errorf("%w: empty or absolute segment: %q: %s", ErrInvalidLocation, segment, path.Join(prefix...))
+			case ".", "..":
+				return fmt.Errorf("%w: relative segment: %q: %s", ErrInvalidLocation, segment, path.Join(prefix...))
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,58 @@
+// Copyright (C) 2025 ZedCloud Org.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package filesystem_test
+
+import (
+	"io/fs"
+	"testing"
+
+	"github.com/pluto-org-co/fsio/filesystem"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_ValidateLocation(t *testing.T) {
+	type Test struct {
+		Name     string
+		Location []string
+		Valid    bool
+	}
+	var tests = []Test{
+		{Name: "Simple", Location: []string{"dir", "file.txt"}, Valid: true},
+		{Name: "Dots", Location: []string{"...", ".hidden", "file..txt"}, Valid: true},
+		{Name: "Nested", Location: []string{"dir/sub", "file.txt"}, Valid: true},
+		{Name: "Empty", Location: []string{}, Valid: false},
+		{Name: "EmptySegment", Location: []string{"dir", "", "file.txt"}, Valid: false},
+		{Name: "Current", Location: []string{".", "file.txt"}, Valid: false},
+		{Name: "Parent", Location: []string{"..", "..", "etc", "passwd"}, Valid: false},
+		{Name: "NestedParent", Location: []string{"dir/../..", "file.txt"}, Valid: false},
+		{Name: "Absolute", Location: []string{"/etc", "passwd"}, Valid: false},
+		{Name: "Trailing", Location: []string{"dir/", "file.txt"}, Valid: false},
+		{Name: "NUL", Location: []string{"file\x00.txt"}, Valid: false},
+	}
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			assertions := assert.New(t)
+
+			err := filesystem.ValidateLocation(test.Location)
+			if test.Valid {
+				assertions.Nil(err, "expecting valid location")
+				return
+			}
+			assertions.ErrorIs(err, filesystem.ErrInvalidLocation, "expecting invalid location")
+			assertions.ErrorIs(err, fs.ErrInvalid, "expecting fs invalid")
+		})
+	}
+}
@@ -33,7 +33,16 @@ type PathMod struct {
 	f  PathModFunc
 }
 
-var _ filesystem.Filesystem = (*PathMod)(nil)
+var (
+	_ filesystem.Filesystem           = (*PathMod)(nil)
+	_ filesystem.CapabilitiesReporter = (*PathMod)(nil)
+	_ filesystem.RangeReader          = (*PathMod)(nil)
+	_ filesystem.Creator              = (*PathMod)(nil)
+)
+
+func (p *PathMod) Capabilities() (caps filesystem.Capability) {
+	return filesystem.Capabilities(p.fs)
+}
 
 func (p *PathMod) ChecksumTime(ctx context.Context, location []string) (checksum string, err error) {
 	return p.fs.ChecksumTime(ctx, location)
@@ -43,18 +52,38 @@ func (p *PathMod) ChecksumSha256(ctx context.Context, location []string) (checks
 	return p.fs.ChecksumSha256(ctx, location)
 }
 
-func (p *PathMod) Files(ctx context.Context) (seq iter.Seq[filesystem.FileEntry]) {
-	return p.fs.Files(ctx)
+func (p *PathMod) Stat(ctx context.Context, location []string) (info *filesystem.FileInfo, err error) {
+	return p.fs.Stat(ctx, location)
+}
+
+func (p *PathMod) Files(ctx context.Context, options ...filesystem.ListOption) (seq iter.Seq2[filesystem.FileEntry, error]) {
+	return p.fs.Files(ctx, options...)
 }
 
 func (p *PathMod) Open(ctx context.Context, location []string) (rc io.ReadCloser, err error) {
 	return p.fs.Open(ctx, location)
 }
 
+func (p *PathMod) OpenRange(ctx context.Context, location []string, offset, length int64) (rc io.ReadCloser, err error) {
+	return filesystem.OpenRange(ctx, p.fs, location, offset, length)
+}
+
 func (p *PathMod) WriteFile(ctx context.Context, location []string, src io.Reader, modTime time.Time) (finalLocation []string, err error) {
+	err = filesystem.ValidateLocation(location)
+	if err != nil {
+		return nil, err
+	}
 	return p.fs.WriteFile(ctx, p.f(location), src, modTime)
 }
 
+func (p *PathMod) Create(ctx context.Context, location []string, modTime time.Time) (w filesystem.FileWriter, err error) {
+	err = filesystem.ValidateLocation(location)
+	if err != nil {
+		return nil, err
+	}
+	return filesystem.Create(ctx, p.fs, p.f(location), modTime)
+}
+
 func (p *PathMod) RemoveAll(ctx context.Context, location []string) (err error) {
 	return p.fs.RemoveAll(ctx, location)
 }
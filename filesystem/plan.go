@@ -0,0 +1,355 @@
+// Copyright (C) 2025 ZedCloud Org.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package filesystem
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Operation planned for a single file
+type ActionKind string
+
+const (
+	ActionCreate ActionKind = "create"
+	ActionUpdate ActionKind = "update"
+	ActionSkip   ActionKind = "skip"
+	ActionDelete ActionKind = "delete"
+	ActionMove   ActionKind = "move"
+)
+
+type Action struct {
+	Kind ActionKind `json:"action"`
+	// Location of the file in dst
+	Location []string `json:"location"`
+	// Previous dst location of moved files
+	From   []string `json:"from,omitempty"`
+	Reason string   `json:"reason"`
+	// Bytes expected to be transferred. Negative when unknown
+	Bytes int64 `json:"bytes"`
+	// Modification time written into dst
+	ModTime time.Time `json:"mod-time,omitzero"`
+
+	// Listed src file, recorded in the sync state once the action succeeded
+	entry FileEntry
+}
+
+// Ordered actions of a sync. Transfers and moves come first, followed by the deletions
+type SyncPlan struct {
+	Actions []*Action `json:"actions"`
+	// Sum of the known bytes expected to be transferred
+	Bytes int64 `json:"bytes"`
+}
+
+func (p *SyncPlan) add(action *Action) {
+	p.Actions = append(p.Actions, action)
+	if action.Bytes > 0 {
+		p.Bytes += action.Bytes
+	}
+}
+
+// Removed dst files are matched with new src files by their size and modification time
+type moveKey struct {
+	size    int64
+	modTime int64
+}
+
+func moveKeyOf(entry FileEntry) (key moveKey) {
+	return moveKey{entry.Size(), entry.ModTime().Unix()}
+}
+
+// Confirms the move candidate holds the src contents, comparing the backend hashes when both report one
+// and the sha256 checksums otherwise. Unconfirmed candidates are never moved
+func sameContents(ctx context.Context, dst, src Filesystem, dstLocation, srcLocation []string) (ok bool) {
+	srcInfo, err := src.Stat(ctx, srcLocation)
+	if err != nil {
+		return false
+	}
+	dstInfo, err := dst.Stat(ctx, dstLocation)
+	if err != nil {
+		return false
+	}
+
+	srcTag := strings.Trim(srcInfo.ETag, `"`)
+	dstTag := strings.Trim(dstInfo.ETag, `"`)
+	if srcTag != "" && strings.EqualFold(srcTag, dstTag) {
+		return true
+	}
+
+	// Tags may differ for the same contents, like the ETag of multipart uploads
+	srcChecksum, err := src.ChecksumSha256(ctx, srcLocation)
+	if err != nil {
+		return false
+	}
+	dstChecksum, err := dst.ChecksumSha256(ctx, dstLocation)
+	if err != nil {
+		return false
+	}
+	return srcChecksum == dstChecksum
+}
+
+// Compares src and dst returning the actions Sync would perform with the same options, without modifying any file.
+// Decisions are taken from both listings: files left unchanged following the compare strategy are skipped.
+// When mirroring, a new src file matching the size and modification time of a single removed dst file is planned as a move.
+// The plan is always returned. err reports incomplete listings or refused deletions, in which case no deletion is planned
+func Plan(ctx context.Context, dst, src Filesystem, options ...SyncOption) (plan *SyncPlan, err error) {
+	return NewSyncCtx(options...).plan(ctx, dst, src, time.Now())
+}
+
+func (s *SyncCtx) plan(ctx context.Context, dst, src Filesystem, start time.Time) (plan *SyncPlan, err error) {
+	plan = &SyncPlan{}
+
+	dstFiles := map[string]FileEntry{}
+	for entry, err := range dst.Files(ctx, s.ListOptions...) {
+		if err != nil {
+			return plan, fmt.Errorf("failed to list dst files: %w: %w", ErrIncompleteListing, err)
+		}
+		if !s.Filter.Match(entry) {
+			continue
+		}
+		dstFiles[path.Join(entry.Location()...)] = entry
+	}
+
+	var (
+		srcEntries []FileEntry
+		srcFiles   = map[string]struct{}{}
+		limited    bool
+		listErr    error
+	)
+	for entry, err := range src.Files(ctx, s.ListOptions...) {
+		if err != nil {
+			listErr = fmt.Errorf("failed to list src files: %w: %w", ErrIncompleteListing, err)
+			break
+		}
+
+		if !s.Filter.Match(entry) {
+			continue
+		}
+		if s.MaxFiles > 0 && int64(len(srcEntries)) >= s.MaxFiles {
+			limited = true
+			break
+		}
+		srcEntries = append(srcEntries, entry)
+		s.notify(Event{Kind: EventListed, Location: entry.Location(), Bytes: entry.Size()})
+		srcFiles[path.Join(entry.Location()...)] = struct{}{}
+	}
+
+	// Only complete listings can tell which dst files are no longer in src
+	mirroring := s.Mirror && listErr == nil && !limited
+
+	var orphans []FileEntry
+	if mirroring {
+		for filename, entry := range dstFiles {
+			_, found := srcFiles[filename]
+			if !found {
+				orphans = append(orphans, entry)
+			}
+		}
+		slices.SortFunc(orphans, func(a, b FileEntry) int {
+			return strings.Compare(path.Join(a.Location()...), path.Join(b.Location()...))
+		})
+	}
+
+	candidates := map[moveKey][]FileEntry{}
+	if mirroring && Capabilities(dst).Has(CapabilityMove) {
+		for _, entry := range orphans {
+			// Empty files can't be told apart
+			if entry.Size() <= 0 {
+				continue
+			}
+			key := moveKeyOf(entry)
+			candidates[key] = append(candidates[key], entry)
+		}
+	}
+
+	moved := map[string]struct{}{}
+	for _, entry := range srcEntries {
+		action := &Action{
+			Location: entry.Location(),
+			Bytes:    entry.Size(),
+			ModTime:  s.modTimeOf(entry, start),
+			entry:    entry,
+		}
+
+		var (
+			same       bool
+			compareErr error
+		)
+		dstEntry, found := dstFiles[path.Join(entry.Location()...)]
+		synchronized := found && s.synchronized(entry)
+		if found && !synchronized {
+			same, compareErr = s.unchangedEntries(ctx, dst, src, entry, dstEntry)
+		}
+
+		switch {
+		case synchronized:
+			action.Kind = ActionSkip
+			action.Reason = "unchanged since the last sync"
+			action.Bytes = 0
+			action.ModTime = time.Time{}
+		case found && compareErr != nil:
+			action.Kind = ActionUpdate
+			action.Reason = fmt.Sprintf("failed to compare %s: %v", s.Compare.describe(), compareErr)
+		case found && same:
+			action.Kind = ActionSkip
+			action.Reason = "same " + s.Compare.describe()
+			action.Bytes = 0
+			action.ModTime = time.Time{}
+		case found:
+			action.Kind = ActionUpdate
+			action.Reason = s.Compare.describe() + " changed"
+		default:
+			key := moveKeyOf(entry)
+			matches := candidates[key]
+			if len(matches) == 1 && sameContents(ctx, dst, src, matches[0].Location(), entry.Location()) {
+				delete(candidates, key)
+				moved[path.Join(matches[0].Location()...)] = struct{}{}
+
+				action.Kind = ActionMove
+				action.From = matches[0].Location()
+				action.Reason = "same contents as file missing in src"
+				action.Bytes = 0
+				action.ModTime = time.Time{}
+				break
+			}
+
+			action.Kind = ActionCreate
+			action.Reason = "missing in dst"
+		}
+		plan.add(action)
+	}
+
+	switch {
+	case !s.Mirror:
+		return plan, listErr
+	case listErr != nil:
+		return plan, listErr
+	case limited:
+		return plan, fmt.Errorf("refusing to mirror src listing limited to %d files: %w", s.MaxFiles, ErrIncompleteListing)
+	}
+
+	var deletions []*Action
+	for _, entry := range orphans {
+		_, found := moved[path.Join(entry.Location()...)]
+		if found {
+			continue
+		}
+		deletions = append(deletions, &Action{
+			Kind:     ActionDelete,
+			Location: entry.Location(),
+			Reason:   "missing in src",
+		})
+	}
+
+	if len(deletions) == 0 {
+		return plan, nil
+	}
+
+	percent := 100 * float64(len(deletions)) / float64(len(dstFiles))
+	if percent > s.MaxDeletePercent {
+		return plan, fmt.Errorf("refusing to remove %d of %d dst files (%.1f%% > %.1f%%): %w",
+			len(deletions), len(dstFiles), percent, s.MaxDeletePercent, ErrTooManyDeletions)
+	}
+
+	for _, action := range deletions {
+		plan.add(action)
+	}
+	return plan, nil
+}
+
+// Performs the planned actions recording their outcome. Deletions only start once every transfer and move finished
+func applyPlan(ctx context.Context, dst, src Filesystem, syncCtx *SyncCtx, plan *SyncPlan, result *SyncResult) (err error) {
+	pool := newWorkerPool(ctx, syncCtx, result)
+	defer pool.Close()
+
+	var deleting bool
+	for _, action := range plan.Actions {
+		var operation fileOperation
+		switch action.Kind {
+		case ActionCreate, ActionUpdate:
+			operation = func(ctx context.Context) (kind ActionKind, transferred int64, err error) {
+				transferred, err = transferFile(ctx, syncCtx, dst, src, action.Kind, action.Location, action.ModTime)
+				if err == nil {
+					err = syncCtx.remember(action.Kind, action.entry, nil)
+				}
+				if err != nil {
+					return action.Kind, transferred, fmt.Errorf("failed to sync: %s: %w", path.Join(action.Location...), err)
+				}
+				return action.Kind, transferred, nil
+			}
+		case ActionMove:
+			operation = func(ctx context.Context) (kind ActionKind, transferred int64, err error) {
+				syncCtx.notify(Event{Kind: EventStarted, Action: action.Kind, Location: action.Location})
+
+				_, err = dst.Move(ctx, action.From, action.Location)
+				if err == nil {
+					err = syncCtx.remember(action.Kind, action.entry, action.From)
+				}
+				if err != nil {
+					return action.Kind, 0, fmt.Errorf("failed to move: %s: %s: %w", path.Join(action.From...), path.Join(action.Location...), err)
+				}
+				return action.Kind, 0, nil
+			}
+		case ActionDelete:
+			if !deleting {
+				err = pool.Wait()
+				if err != nil {
+					return err
+				}
+				deleting = true
+			}
+
+			operation = func(ctx context.Context) (kind ActionKind, transferred int64, err error) {
+				syncCtx.notify(Event{Kind: EventStarted, Action: action.Kind, Location: action.Location})
+
+				err = dst.RemoveAll(ctx, action.Location)
+				if err == nil {
+					err = syncCtx.forget(action.Location)
+				}
+				if err != nil {
+					return action.Kind, 0, fmt.Errorf("failed to remove: %s: %w", path.Join(action.Location...), err)
+				}
+				return action.Kind, 0, nil
+			}
+		default:
+			operation = func(ctx context.Context) (kind ActionKind, transferred int64, err error) {
+				err = syncCtx.remember(action.Kind, action.entry, nil)
+				if err != nil {
+					return action.Kind, 0, fmt.Errorf("failed to skip: %s: %w", path.Join(action.Location...), err)
+				}
+				return action.Kind, 0, nil
+			}
+		}
+
+		if !pool.Go(action.Location, operation) {
+			break
+		}
+	}
+
+	err = pool.Wait()
+	switch {
+	case err != nil:
+		return err
+	case ctx.Err() != nil:
+		return fmt.Errorf("failed to sync due to context error: %w", ctx.Err())
+	default:
+		return nil
+	}
+}
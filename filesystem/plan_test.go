@@ -0,0 +1,211 @@
+// Copyright (C) 2025 ZedCloud Org.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package filesystem_test
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"os"
+	"path"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pluto-org-co/fsio/filesystem"
+	"github.com/pluto-org-co/fsio/filesystem/directory"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Plan(t *testing.T) {
+	if os.Getuid() == 0 {
+		t.Skip("Can't run this test as root")
+		return
+	}
+
+	assertions := assert.New(t)
+
+	ctx, cancel := context.WithTimeout(context.TODO(), time.Minute)
+	defer cancel()
+
+	src := directory.New(t.TempDir(), 0o777, 0o777)
+	dst := directory.New(t.TempDir(), 0o777, 0o777)
+
+	modTime := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	write := func(fs filesystem.Filesystem, location []string, contents string, modTime time.Time) (ok bool) {
+		_, err := fs.WriteFile(ctx, location, strings.NewReader(contents), modTime)
+		return assertions.Nil(err, "failed to write file")
+	}
+
+	for index, name := range []string{"kept.txt", "updated.txt", "old-name.txt", "removed.txt"} {
+		if !write(src, []string{"docs", name}, strings.Repeat(name, index+1), modTime) {
+			return
+		}
+	}
+	_, err := filesystem.Sync(ctx, dst, src)
+	if !assertions.Nil(err, "failed to sync files") {
+		return
+	}
+
+	// Changes done to the src after the first sync
+	if !write(src, []string{"docs", "updated.txt"}, "new contents", modTime.Add(time.Hour)) {
+		return
+	}
+	if !write(src, []string{"docs", "created.txt"}, "created", modTime) {
+		return
+	}
+	_, err = src.Move(ctx, []string{"docs", "old-name.txt"}, []string{"docs", "new-name.txt"})
+	if !assertions.Nil(err, "failed to move file") {
+		return
+	}
+	err = src.RemoveAll(ctx, []string{"docs", "removed.txt"})
+	if !assertions.Nil(err, "failed to remove file") {
+		return
+	}
+
+	options := []filesystem.SyncOption{
+		filesystem.WithSyncOptionMirror(true),
+		filesystem.WithSyncOptionMaxDeletePercent(100),
+	}
+
+	plan, err := filesystem.Plan(ctx, dst, src, options...)
+	if !assertions.Nil(err, "failed to plan sync") {
+		return
+	}
+
+	kinds := map[string]filesystem.ActionKind{}
+	var deleted bool
+	for index, action := range plan.Actions {
+		kinds[path.Join(action.Location...)] = action.Kind
+		if action.Kind == filesystem.ActionDelete {
+			deleted = true
+		} else {
+			assertions.False(deleted, "deletions should be planned last: %d", index)
+		}
+		if action.Kind == filesystem.ActionMove {
+			assertions.Equal([]string{"docs", "old-name.txt"}, action.From, "unexpected move origin")
+		}
+	}
+	assertions.Equal(map[string]filesystem.ActionKind{
+		"docs/kept.txt":     filesystem.ActionSkip,
+		"docs/updated.txt":  filesystem.ActionUpdate,
+		"docs/created.txt":  filesystem.ActionCreate,
+		"docs/new-name.txt": filesystem.ActionMove,
+		"docs/removed.txt":  filesystem.ActionDelete,
+	}, kinds, "unexpected plan")
+	assertions.Equal(int64(len("new contents")+len("created")), plan.Bytes, "unexpected transferred bytes")
+
+	contents, err := json.Marshal(plan)
+	if !assertions.Nil(err, "failed to marshal plan") {
+		return
+	}
+	assertions.Contains(string(contents), `"action":"move"`, "plan should be serializable")
+
+	t.Run("DryRun", func(t *testing.T) {
+		assertions := assert.New(t)
+
+		_, err := dst.Stat(ctx, []string{"docs", "removed.txt"})
+		assertions.Nil(err, "planning should not remove files")
+
+		_, err = dst.Stat(ctx, []string{"docs", "created.txt"})
+		assertions.ErrorIs(err, os.ErrNotExist, "planning should not write files")
+	})
+	t.Run("Apply", func(t *testing.T) {
+		assertions := assert.New(t)
+
+		result, err := filesystem.Sync(ctx, dst, src, options...)
+		if !assertions.Nil(err, "failed to mirror files") {
+			return
+		}
+		assertions.Equal(int64(2), result.Copied, "unexpected copied files")
+		assertions.Equal(int64(1), result.Moved, "unexpected moved files")
+		assertions.Equal(int64(1), result.Deleted, "unexpected deleted files")
+		assertions.Equal(int64(1), result.Skipped, "unexpected skipped files")
+		assertions.Equal(plan.Bytes, result.Bytes, "transferred bytes should match the plan")
+
+		plan, err := filesystem.Plan(ctx, dst, src, options...)
+		if !assertions.Nil(err, "failed to plan sync") {
+			return
+		}
+		for _, action := range plan.Actions {
+			assertions.Equal(filesystem.ActionSkip, action.Kind, "mirrored files should be skipped: %v", action.Location)
+		}
+
+		_, err = dst.Stat(ctx, []string{"docs", "old-name.txt"})
+		assertions.ErrorIs(err, os.ErrNotExist, "moved file should not be kept")
+	})
+}
+
+func Test_Plan_MoveContents(t *testing.T) {
+	if os.Getuid() == 0 {
+		t.Skip("Can't run this test as root")
+		return
+	}
+
+	assertions := assert.New(t)
+
+	ctx, cancel := context.WithTimeout(context.TODO(), time.Minute)
+	defer cancel()
+
+	src := directory.New(t.TempDir(), 0o777, 0o777)
+	dst := directory.New(t.TempDir(), 0o777, 0o777)
+
+	modTime := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	_, err := dst.WriteFile(ctx, []string{"removed.txt"}, strings.NewReader("first contents"), modTime)
+	if !assertions.Nil(err, "failed to write dst file") {
+		return
+	}
+	// Same size and modification time, different contents
+	_, err = src.WriteFile(ctx, []string{"created.txt"}, strings.NewReader("other contents"), modTime)
+	if !assertions.Nil(err, "failed to write src file") {
+		return
+	}
+
+	options := []filesystem.SyncOption{
+		filesystem.WithSyncOptionMirror(true),
+		filesystem.WithSyncOptionMaxDeletePercent(100),
+	}
+	plan, err := filesystem.Plan(ctx, dst, src, options...)
+	if !assertions.Nil(err, "failed to plan sync") {
+		return
+	}
+
+	kinds := map[string]filesystem.ActionKind{}
+	for _, action := range plan.Actions {
+		kinds[path.Join(action.Location...)] = action.Kind
+	}
+	assertions.Equal(map[string]filesystem.ActionKind{
+		"created.txt": filesystem.ActionCreate,
+		"removed.txt": filesystem.ActionDelete,
+	}, kinds, "different contents should not be moved")
+
+	_, err = filesystem.Sync(ctx, dst, src, options...)
+	if !assertions.Nil(err, "failed to mirror files") {
+		return
+	}
+
+	file, err := dst.Open(ctx, []string{"created.txt"})
+	if !assertions.Nil(err, "failed to open file") {
+		return
+	}
+	defer file.Close()
+
+	contents, err := io.ReadAll(file)
+	if !assertions.Nil(err, "failed to read file") {
+		return
+	}
+	assertions.Equal("other contents", string(contents), "src contents should be copied")
+}
@@ -50,7 +50,10 @@ func New(locations [][]string, fileSizes int64) (r *Random) {
 	return r
 }
 
-var _ filesystem.Filesystem = (*Random)(nil)
+var (
+	_ filesystem.Filesystem  = (*Random)(nil)
+	_ filesystem.RangeReader = (*Random)(nil)
+)
 
 func (r *Random) ChecksumTime(ctx context.Context, location []string) (checksum string, err error) {
 	return ioutils.ChecksumTime(time.Now()), nil
@@ -70,17 +73,47 @@ func (r *Random) ChecksumSha256(ctx context.Context, location []string) (checksu
 	return checksum, nil
 }
 
-func (r *Random) Files(ctx context.Context) (seq iter.Seq[filesystem.FileEntry]) {
-	return func(yield func(filesystem.FileEntry) bool) {
+func (r *Random) Stat(ctx context.Context, location []string) (info *filesystem.FileInfo, err error) {
+	err = filesystem.ValidateLocation(location)
+	if err != nil {
+		return nil, err
+	}
+
+	_, found := r.locations[path.Join(location...)]
+	if !found {
+		return nil, os.ErrNotExist
+	}
+
+	info = &filesystem.FileInfo{
+		Location:    location,
+		Size:        r.fileSizes,
+		ModTime:     time.Now(),
+		ContentType: "application/octet-stream",
+	}
+	return info, nil
+}
+
+func (r *Random) Files(ctx context.Context, options ...filesystem.ListOption) (seq iter.Seq2[filesystem.FileEntry, error]) {
+	listCtx := filesystem.NewListCtx(options...)
+
+	return func(yield func(filesystem.FileEntry, error) bool) {
 		for location := range r.locations {
 			select {
 			case <-ctx.Done():
+				yield(nil, ctx.Err())
 				return
 			default:
+				locationParts := strings.Split(location, "/")
+				if !listCtx.Match(locationParts) {
+					continue
+				}
+
 				if !yield(&filesystem.SimpleFileEntry{
-					LocationValue: strings.Split(location, "/"),
-					ModTimeValue:  time.Date(2005, 01, 01, 01, 0, 0, 0, time.UTC),
-				}) {
+					LocationValue:    locationParts,
+					ModTimeValue:     time.Date(2005, 01, 01, 01, 0, 0, 0, time.UTC),
+					SizeValue:        r.fileSizes,
+					ContentTypeValue: "application/octet-stream",
+				}, nil) {
 					return
 				}
 			}
@@ -89,6 +122,11 @@ func (r *Random) Files(ctx context.Context) (seq iter.Seq[filesystem.FileEntry])
 }
 
 func (r *Random) Open(ctx context.Context, location []string) (rc io.ReadCloser, err error) {
+	err = filesystem.ValidateLocation(location)
+	if err != nil {
+		return nil, err
+	}
+
 	_, found := r.locations[path.Join(location...)]
 	if !found {
 		return nil, os.ErrNotExist
@@ -98,7 +136,32 @@ func (r *Random) Open(ctx context.Context, location []string) (rc io.ReadCloser,
 	return rc, nil
 }
 
+func (r *Random) OpenRange(ctx context.Context, location []string, offset, length int64) (rc io.ReadCloser, err error) {
+	err = filesystem.ValidateLocation(location)
+	if err != nil {
+		return nil, err
+	}
+
+	_, found := r.locations[path.Join(location...)]
+	if !found {
+		return nil, os.ErrNotExist
+	}
+
+	remaining := max(r.fileSizes-offset, 0)
+	if length >= 0 {
+		remaining = min(remaining, length)
+	}
+
+	rc = io.NopCloser(bufio.NewReader(io.LimitReader(random.InsecureReader, remaining)))
+	return rc, nil
+}
+
 func (r *Random) WriteFile(ctx context.Context, location []string, src io.Reader, modTime time.Time) (finalLocation []string, err error) {
+	err = filesystem.ValidateLocation(location)
+	if err != nil {
+		return nil, err
+	}
+
 	filename := path.Join(location...)
 	r.locations[filename] = struct{}{}
 
@@ -119,11 +182,26 @@ func (r *Random) WriteFile(ctx context.Context, location []string, src io.Reader
 }
 
 func (r *Random) RemoveAll(ctx context.Context, location []string) (err error) {
-	delete(r.locations, path.Join(location...))
+	err = filesystem.ValidateLocation(location)
+	if err != nil {
+		return err
+	}
+
+	filename := path.Join(location...)
+	for existing := range r.locations {
+		if existing == filename || strings.HasPrefix(existing, filename+"/") {
+			delete(r.locations, existing)
+		}
+	}
 	return nil
 }
 
 func (r *Random) Move(ctx context.Context, oldLocation, newLocation []string) (finalLocation []string, err error) {
+	err = filesystem.ValidateLocation(newLocation)
+	if err != nil {
+		return nil, err
+	}
+
 	delete(r.locations, path.Join(oldLocation...))
 	r.locations[path.Join(newLocation...)] = struct{}{}
 	return newLocation, nil
@@ -0,0 +1,79 @@
+// Copyright (C) 2025 ZedCloud Org.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package filesystem
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+
+	"github.com/pluto-org-co/fsio/filesystem/utils"
+)
+
+// Opens length bytes of the passed file starting at offset. A negative length reads until the end of the file.
+// Filesystems not implementing RangeReader fallback to OpenRangeFallback
+func OpenRange(ctx context.Context, reader Reader, location []string, offset, length int64) (rc io.ReadCloser, err error) {
+	if offset < 0 {
+		return nil, fmt.Errorf("invalid offset: %d: %w", offset, fs.ErrInvalid)
+	}
+
+	rangeReader, ok := reader.(RangeReader)
+	if ok {
+		return rangeReader.OpenRange(ctx, location, offset, length)
+	}
+	return OpenRangeFallback(ctx, reader, location, offset, length)
+}
+
+// Implements ranged reads on top of Open by skipping the first offset bytes of the file.
+// Reading past the end of the file results in an empty reader
+func OpenRangeFallback(ctx context.Context, reader Reader, location []string, offset, length int64) (rc io.ReadCloser, err error) {
+	if offset < 0 {
+		return nil, fmt.Errorf("invalid offset: %d: %w", offset, fs.ErrInvalid)
+	}
+
+	file, err := reader.Open(ctx, location)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open file: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			file.Close()
+		}
+	}()
+
+	if offset > 0 {
+		seeker, ok := file.(io.Seeker)
+		if ok {
+			_, err = seeker.Seek(offset, io.SeekStart)
+			if err != nil {
+				return nil, fmt.Errorf("failed to seek: %w", err)
+			}
+		} else {
+			_, err = io.CopyN(io.Discard, file, offset)
+			if err != nil && !errors.Is(err, io.EOF) {
+				return nil, fmt.Errorf("failed to skip contents: %w", err)
+			}
+			err = nil
+		}
+	}
+
+	if length < 0 {
+		return file, nil
+	}
+	return utils.NewSeparateReadCloser(file, io.LimitReader(file, length)), nil
+}
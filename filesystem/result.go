@@ -0,0 +1,62 @@
+// Copyright (C) 2025 ZedCloud Org.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package filesystem
+
+import (
+	"errors"
+	"time"
+)
+
+// Summary of a sync
+type SyncResult struct {
+	// Files created or updated in dst
+	Copied int64
+	// Files moved inside dst
+	Moved int64
+	// Files removed from dst
+	Deleted int64
+	// Files already up to date in dst or no longer found in src
+	Skipped int64
+	// Files whose operation failed
+	Failed int64
+	// Attempts repeated after retryable failures
+	Retried int64
+	// Locations modified on both sides of a BiSync, or modified on one side and removed from the other
+	Conflicts [][]string
+	// Bytes read from src while copying
+	Bytes    int64
+	Duration time.Duration
+	// Failure of every failed file
+	Errors []error
+}
+
+// Joined failures of every failed file. Nil when no file failed
+func (r *SyncResult) Err() (err error) {
+	return errors.Join(r.Errors...)
+}
+
+func (r *SyncResult) record(kind ActionKind) {
+	switch kind {
+	case ActionCreate, ActionUpdate:
+		r.Copied++
+	case ActionMove:
+		r.Moved++
+	case ActionDelete:
+		r.Deleted++
+	case ActionSkip:
+		r.Skipped++
+	}
+}
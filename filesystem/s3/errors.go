@@ -0,0 +1,52 @@
+// Copyright (C) 2025 ZedCloud Org.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package s3
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/http"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/pluto-org-co/fsio/filesystem"
+)
+
+// Wraps the minio error responses with the filesystem errors
+func wrapError(err error) error {
+	var res minio.ErrorResponse
+	if !errors.As(err, &res) {
+		return err
+	}
+
+	switch {
+	// A missing bucket fails every operation, it must not be confused with a missing object
+	case res.Code == minio.NoSuchBucket:
+		return fmt.Errorf("bucket not found: %w", err)
+	case res.Code == minio.NoSuchKey || res.Code == minio.NoSuchVersion || res.StatusCode == http.StatusNotFound:
+		return fmt.Errorf("%w: %w", fs.ErrNotExist, err)
+	case res.Code == minio.AccessDenied || res.Code == minio.AllAccessDisabled ||
+		res.StatusCode == http.StatusForbidden:
+		return fmt.Errorf("%w: %w", fs.ErrPermission, err)
+	case res.Code == "SlowDown" || res.Code == "SlowDownRead" || res.Code == "SlowDownWrite" ||
+		res.StatusCode == http.StatusTooManyRequests || res.StatusCode == http.StatusServiceUnavailable:
+		return fmt.Errorf("%w: %w", filesystem.ErrRateLimited, err)
+	case res.Code == "InternalError" || res.StatusCode >= http.StatusInternalServerError:
+		return fmt.Errorf("%w: %w", filesystem.ErrTransient, err)
+	default:
+		return err
+	}
+}
@@ -17,12 +17,15 @@ package s3
 
 import (
 	"bufio"
+	"bytes"
 	"context"
 	"crypto/sha256"
 	"encoding/base64"
 	"encoding/hex"
+	"errors"
 	"fmt"
 	"io"
+	"io/fs"
 	"iter"
 	"os"
 	"path"
@@ -51,17 +54,33 @@ func New(client *minio.Client, bucket string, cacheExpiry time.Duration) (s *S3)
 	}
 }
 
-var _ filesystem.Filesystem = (*S3)(nil)
+var (
+	_ filesystem.Filesystem  = (*S3)(nil)
+	_ filesystem.RangeReader = (*S3)(nil)
+	_ filesystem.Creator     = (*S3)(nil)
+)
+
+// Key of the object stored at the location
+func objectKeyOf(location []string) (objectKey string, err error) {
+	err = filesystem.ValidateLocation(location)
+	if err != nil {
+		return "", err
+	}
+	return path.Join(location...), nil
+}
 
 func (s *S3) ChecksumTime(ctx context.Context, location []string) (checksum string, err error) {
-	objectKey := path.Join(location...)
+	objectKey, err := objectKeyOf(location)
+	if err != nil {
+		return "", err
+	}
 
 	options := minio.StatObjectOptions{
 		Checksum: true,
 	}
 	objInfo, err := s.client.StatObject(ctx, s.bucket, objectKey, options)
 	if err != nil {
-		return "", fmt.Errorf("failed to get object information: %w", err)
+		return "", fmt.Errorf("failed to get object information: %w", wrapError(err))
 	}
 
 	checksum = ioutils.ChecksumTime(LastModifiedFromObj(&objInfo))
@@ -69,14 +88,17 @@ func (s *S3) ChecksumTime(ctx context.Context, location []string) (checksum stri
 }
 
 func (s *S3) ChecksumSha256(ctx context.Context, location []string) (checksum string, err error) {
-	objectKey := path.Join(location...)
+	objectKey, err := objectKeyOf(location)
+	if err != nil {
+		return "", err
+	}
 
 	options := minio.StatObjectOptions{
 		Checksum: true,
 	}
 	info, err := s.client.StatObject(ctx, s.bucket, objectKey, options)
 	if err != nil {
-		return "", fmt.Errorf("failed to get object information: %w", err)
+		return "", fmt.Errorf("failed to get object information: %w", wrapError(err))
 	}
 
 	checksum = info.ChecksumSHA256
@@ -98,6 +120,29 @@ func (s *S3) ChecksumSha256(ctx context.Context, location []string) (checksum st
 	return checksum, nil
 }
 
+func (s *S3) Stat(ctx context.Context, location []string) (info *filesystem.FileInfo, err error) {
+	objectKey, err := objectKeyOf(location)
+	if err != nil {
+		return nil, err
+	}
+
+	objInfo, err := s.client.StatObject(ctx, s.bucket, objectKey, minio.StatObjectOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get object information: %w", wrapError(err))
+	}
+
+	info = &filesystem.FileInfo{
+		Location:    location,
+		Size:        objInfo.Size,
+		ModTime:     LastModifiedFromObj(&objInfo),
+		ContentType: objInfo.ContentType,
+		ETag:        objInfo.ETag,
+		Version:     objInfo.VersionID,
+		ID:          objInfo.Key,
+	}
+	return info, nil
+}
+
 const (
 	XAmzMetaMTime   = "X-Amz-Meta-Mtime"
 	XAmzCustomMTime = "X-Amz-Custom-Mtime"
@@ -129,28 +174,49 @@ func LastModifiedFromObj(obj *minio.ObjectInfo) (lastModified time.Time) {
 	return lastModified
 }
 
-func (s *S3) Files(ctx context.Context) (seq iter.Seq[filesystem.FileEntry]) {
-	options := minio.ListObjectsOptions{
+func (s *S3) Files(ctx context.Context, options ...filesystem.ListOption) (seq iter.Seq2[filesystem.FileEntry, error]) {
+	listCtx := filesystem.NewListCtx(options...)
+
+	err := filesystem.ValidatePrefix(listCtx.Prefix)
+	if err != nil {
+		return func(yield func(filesystem.FileEntry, error) bool) {
+			yield(nil, err)
+		}
+	}
+
+	listOptions := minio.ListObjectsOptions{
 		WithMetadata: true,
-		Recursive:    true,
+		Recursive:    listCtx.Recursive,
+	}
+	if len(listCtx.Prefix) > 0 {
+		listOptions.Prefix = path.Join(listCtx.Prefix...) + "/"
 	}
 
-	objInfoIter := s.client.ListObjectsIter(ctx, s.bucket, options)
+	objInfoIter := s.client.ListObjectsIter(ctx, s.bucket, listOptions)
 
-	return func(yield func(filesystem.FileEntry) bool) {
+	return func(yield func(filesystem.FileEntry, error) bool) {
 		for objInfo := range objInfoIter {
 			if objInfo.Err != nil {
+				yield(nil, fmt.Errorf("failed to list objects: %w", wrapError(objInfo.Err)))
 				return
 			}
 
+			// Common prefixes are reported when listing non recursively
+			if strings.HasSuffix(objInfo.Key, "/") {
+				continue
+			}
+
 			lastModified := LastModifiedFromObj(&objInfo)
 
 			entry := &filesystem.SimpleFileEntry{
-				LocationValue: strings.Split(objInfo.Key, "/"),
-				ModTimeValue:  lastModified,
+				LocationValue:    strings.Split(objInfo.Key, "/"),
+				ModTimeValue:     lastModified,
+				SizeValue:        objInfo.Size,
+				ContentTypeValue: objInfo.ContentType,
+				SysValue:         objInfo,
 			}
 
-			if !yield(entry) {
+			if !yield(entry, nil) {
 				return
 			}
 		}
@@ -158,7 +224,10 @@ func (s *S3) Files(ctx context.Context) (seq iter.Seq[filesystem.FileEntry]) {
 }
 
 func (s *S3) Open(ctx context.Context, location []string) (rc io.ReadCloser, err error) {
-	objectKey := path.Join(location...)
+	objectKey, err := objectKeyOf(location)
+	if err != nil {
+		return nil, err
+	}
 
 	rawFilePathChecksum := sha256.Sum256([]byte(objectKey))
 	filePathChecksum := hex.EncodeToString(rawFilePathChecksum[:])
@@ -192,7 +261,7 @@ func (s *S3) Open(ctx context.Context, location []string) (rc io.ReadCloser, err
 
 	obj, err := s.client.GetObject(ctx, s.bucket, objectKey, minio.GetObjectOptions{})
 	if err != nil {
-		return nil, fmt.Errorf("failed to get object: %w", err)
+		return nil, fmt.Errorf("failed to get object: %w", wrapError(err))
 	}
 
 	writer := bufio.NewWriterSize(cachedFile, ioutils.DefaultBufferSize)
@@ -200,7 +269,7 @@ func (s *S3) Open(ctx context.Context, location []string) (rc io.ReadCloser, err
 
 	_, err = ioutils.CopyContext(ctx, writer, reader, ioutils.DefaultBufferSize)
 	if err != nil {
-		return nil, fmt.Errorf("failed to copy contents: %w", err)
+		return nil, fmt.Errorf("failed to copy contents: %w", wrapError(err))
 	}
 
 	err = writer.Flush()
@@ -220,8 +289,48 @@ func (s *S3) Open(ctx context.Context, location []string) (rc io.ReadCloser, err
 	return cachedFile, nil
 }
 
+func (s *S3) OpenRange(ctx context.Context, location []string, offset, length int64) (rc io.ReadCloser, err error) {
+	if offset < 0 {
+		return nil, fmt.Errorf("invalid offset: %d: %w", offset, fs.ErrInvalid)
+	}
+
+	objectKey, err := objectKeyOf(location)
+	if err != nil {
+		return nil, err
+	}
+
+	// The size is required for clamping the range, since S3 rejects ranges starting after the end of the object
+	objInfo, err := s.client.StatObject(ctx, s.bucket, objectKey, minio.StatObjectOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get object information: %w", wrapError(err))
+	}
+
+	end := objInfo.Size
+	if length >= 0 {
+		end = min(end, offset+length)
+	}
+	if offset >= end {
+		return io.NopCloser(strings.NewReader("")), nil
+	}
+
+	options := minio.GetObjectOptions{}
+	err = options.SetRange(offset, end-1)
+	if err != nil {
+		return nil, fmt.Errorf("failed to set range: %w", err)
+	}
+
+	obj, err := s.client.GetObject(ctx, s.bucket, objectKey, options)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get object: %w", wrapError(err))
+	}
+	return obj, nil
+}
+
 func (s *S3) WriteFile(ctx context.Context, location []string, src io.Reader, modTime time.Time) (finalLocation []string, err error) {
-	objectKey := path.Join(location...)
+	objectKey, err := objectKeyOf(location)
+	if err != nil {
+		return nil, err
+	}
 
 	srcAsFile, err := ioutils.ReaderToTempFile(ctx, src)
 	if err != nil {
@@ -261,33 +370,135 @@ func (s *S3) WriteFile(ctx context.Context, location []string, src io.Reader, mo
 		},
 	)
 	if err != nil {
-		return nil, fmt.Errorf("failed to put object: %w", err)
+		return nil, fmt.Errorf("failed to put object: %w", wrapError(err))
 	}
 
 	return location, nil
 }
 
+const (
+	// Size of the parts used by streaming uploads, since the final size of the object is unknown
+	StreamPartSize = 16 * 1024 * 1024
+	// Bytes required by mimetype for detecting the content type
+	mimeDetectSize = 3072
+)
+
+// Streams the written contents with a multipart upload. Aborting the writer aborts the upload
+func (s *S3) Create(ctx context.Context, location []string, modTime time.Time) (w filesystem.FileWriter, err error) {
+	objectKey, err := objectKeyOf(location)
+	if err != nil {
+		return nil, err
+	}
+	sTime := strconv.FormatInt(modTime.Unix(), 10)
+
+	w = filesystem.NewPipeFileWriter(ctx, location, func(ctx context.Context, src io.Reader) (finalLocation []string, err error) {
+		head := make([]byte, mimeDetectSize)
+		n, err := io.ReadFull(src, head)
+		if err != nil && !errors.Is(err, io.EOF) && !errors.Is(err, io.ErrUnexpectedEOF) {
+			return nil, fmt.Errorf("failed to read contents: %w", err)
+		}
+		head = head[:n]
+
+		_, err = s.client.PutObject(
+			ctx,
+			s.bucket, objectKey,
+			io.MultiReader(bytes.NewReader(head), src),
+			-1,
+			minio.PutObjectOptions{
+				ContentType: mimetype.Detect(head).String(),
+				UserMetadata: map[string]string{
+					XAmzMetaMTime:   sTime,
+					XAmzCustomMTime: sTime,
+				},
+				PartSize: StreamPartSize,
+			},
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to put object: %w", wrapError(err))
+		}
+		return location, nil
+	})
+	return w, nil
+}
+
+// Removes the object and every object under the location, batched with multi object deletes.
+// Missing objects are ignored
 func (s *S3) RemoveAll(ctx context.Context, location []string) (err error) {
-	objectKey := path.Join(location...)
+	objectKey, err := objectKeyOf(location)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	objectsCh := make(chan minio.ObjectInfo)
+	listErrCh := make(chan error, 1)
+	go func() {
+		defer close(objectsCh)
 
-	options := minio.RemoveObjectOptions{}
-	return s.client.RemoveObject(ctx, s.bucket, objectKey, options)
+		select {
+		case objectsCh <- minio.ObjectInfo{Key: objectKey}:
+		case <-ctx.Done():
+			return
+		}
+
+		listOptions := minio.ListObjectsOptions{
+			Prefix:    objectKey + "/",
+			Recursive: true,
+		}
+		for objInfo := range s.client.ListObjectsIter(ctx, s.bucket, listOptions) {
+			if objInfo.Err != nil {
+				listErrCh <- fmt.Errorf("failed to list objects: %w", wrapError(objInfo.Err))
+				return
+			}
+
+			select {
+			case objectsCh <- objInfo:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	var errs []error
+	for removeErr := range s.client.RemoveObjects(ctx, s.bucket, objectsCh, minio.RemoveObjectsOptions{}) {
+		errs = append(errs, fmt.Errorf("failed to remove object: %s: %w", removeErr.ObjectName, wrapError(removeErr.Err)))
+	}
+
+	select {
+	case err = <-listErrCh:
+		errs = append(errs, err)
+	default:
+	}
+
+	err = ctx.Err()
+	if err != nil {
+		errs = append(errs, fmt.Errorf("context error during removal: %w", err))
+	}
+	return errors.Join(errs...)
 }
 
 func (s *S3) Move(ctx context.Context, oldLocation, newLocation []string) (finalLocation []string, err error) {
-	oldObjName := path.Join(oldLocation...)
-	newObjName := path.Join(newLocation...)
+	oldObjName, err := objectKeyOf(oldLocation)
+	if err != nil {
+		return nil, err
+	}
+	newObjName, err := objectKeyOf(newLocation)
+	if err != nil {
+		return nil, err
+	}
 
 	dst := minio.CopyDestOptions{Bucket: s.bucket, Object: newObjName}
 	src := minio.CopySrcOptions{Bucket: s.bucket, Object: oldObjName}
 	_, err = s.client.CopyObject(ctx, dst, src)
 	if err != nil {
-		return nil, fmt.Errorf("failed to copy object: %w", err)
+		return nil, fmt.Errorf("failed to copy object: %w", wrapError(err))
 	}
 
 	err = s.client.RemoveObject(ctx, s.bucket, oldObjName, minio.RemoveObjectOptions{})
 	if err != nil {
-		return nil, fmt.Errorf("failed to remove old object: %w", err)
+		return nil, fmt.Errorf("failed to remove old object: %w", wrapError(err))
 	}
 	return newLocation, nil
 }
@@ -0,0 +1,356 @@
+// Copyright (C) 2025 ZedCloud Org.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package filesystem
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"path"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// Location of the snapshot manifests in the backup filesystem
+	SnapshotManifestsRoot = "manifests"
+	// Location of the snapshot contents in the backup filesystem
+	SnapshotContentsRoot = "snapshots"
+	// Identifier of a snapshot, the UTC time it started at
+	SnapshotIDLayout = "20060102T150405Z"
+)
+
+// Returned when the requested snapshot has no manifest
+var ErrSnapshotNotFound = fmt.Errorf("snapshot not found: %w", fs.ErrNotExist)
+
+// State of a single file in a snapshot
+type SnapshotEntry struct {
+	Location []string `json:"location"`
+	// Bytes stored
+	Size int64 `json:"size"`
+	// Size reported by the src listing, negative when unknown like for exported documents
+	ListedSize int64     `json:"listed-size"`
+	Sha256     string    `json:"sha256"`
+	ModTime    time.Time `json:"mod-time"`
+	// Snapshot storing the contents. Unchanged files point to the snapshot they changed in
+	Snapshot string `json:"snapshot"`
+}
+
+// Every file found in the src of a snapshot
+type SnapshotManifest struct {
+	ID      string
+	Entries []*SnapshotEntry
+}
+
+func snapshotManifestLocation(id string) (location []string) {
+	return []string{SnapshotManifestsRoot, id + ".jsonl"}
+}
+
+func snapshotContentLocation(id string, location []string) (contentLocation []string) {
+	contentLocation = make([]string, 0, 2+len(location))
+	contentLocation = append(contentLocation, SnapshotContentsRoot, id)
+	contentLocation = append(contentLocation, location...)
+	return contentLocation
+}
+
+// Lists the identifiers of the complete snapshots stored in backup, oldest first
+func Snapshots(ctx context.Context, backup Filesystem) (ids []string, err error) {
+	for entry, err := range backup.Files(ctx, WithListOptionPrefix(SnapshotManifestsRoot), WithListOptionRecursive(false)) {
+		if err != nil {
+			return nil, fmt.Errorf("failed to list manifests: %w", err)
+		}
+
+		name := entry.Location()[len(entry.Location())-1]
+		id, found := strings.CutSuffix(name, ".jsonl")
+		if !found {
+			continue
+		}
+		_, err = time.Parse(SnapshotIDLayout, id)
+		if err != nil {
+			continue
+		}
+		ids = append(ids, id)
+	}
+	slices.Sort(ids)
+	return ids, nil
+}
+
+// Reads the manifest of the snapshot
+func LoadSnapshot(ctx context.Context, backup Filesystem, id string) (manifest *SnapshotManifest, err error) {
+	file, err := backup.Open(ctx, snapshotManifestLocation(id))
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, fmt.Errorf("%w: %s", ErrSnapshotNotFound, id)
+		}
+		return nil, fmt.Errorf("failed to open manifest: %w", err)
+	}
+	defer file.Close()
+
+	manifest = &SnapshotManifest{ID: id}
+	decoder := json.NewDecoder(bufio.NewReader(file))
+	for {
+		var entry SnapshotEntry
+		err = decoder.Decode(&entry)
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return manifest, nil
+			}
+			return nil, fmt.Errorf("failed to decode manifest entry: %w", err)
+		}
+		manifest.Entries = append(manifest.Entries, &entry)
+	}
+}
+
+func writeSnapshotManifest(ctx context.Context, backup Filesystem, manifest *SnapshotManifest, modTime time.Time) (err error) {
+	reader, writer := io.Pipe()
+	go func() {
+		buffered := bufio.NewWriter(writer)
+		encoder := json.NewEncoder(buffered)
+		for _, entry := range manifest.Entries {
+			err := encoder.Encode(entry)
+			if err != nil {
+				writer.CloseWithError(err)
+				return
+			}
+		}
+		writer.CloseWithError(buffered.Flush())
+	}()
+	defer reader.Close()
+
+	_, err = backup.WriteFile(ctx, snapshotManifestLocation(manifest.ID), reader, modTime)
+	if err != nil {
+		return fmt.Errorf("failed to write manifest: %w", err)
+	}
+	return nil
+}
+
+// Stores the state of every src file as a new snapshot in backup.
+// Contents are only stored when the listed size or modification time changed since the latest snapshot,
+// the manifest of the new snapshot points to the snapshot holding each file.
+// The manifest is written once every file was processed. Files that failed keep their entry of the latest snapshot,
+// files removed from src after being listed are left out.
+// No manifest is written when the src listing failed, so incomplete snapshots are never listed
+func Snapshot(ctx context.Context, backup, src Filesystem, options ...SyncOption) (manifest *SnapshotManifest, result *SyncResult, err error) {
+	syncCtx := NewSyncCtx(options...)
+
+	start := time.Now()
+	result = &SyncResult{}
+	defer func() {
+		result.Duration = time.Since(start)
+		syncCtx.notify(Event{Kind: EventFinished, Err: err, Result: result})
+	}()
+
+	err = RequireCapabilities(backup, CapabilityRead|CapabilityWrite)
+	if err != nil {
+		return nil, result, fmt.Errorf("invalid backup: %w", err)
+	}
+
+	previous := map[string]*SnapshotEntry{}
+	ids, err := Snapshots(ctx, backup)
+	if err != nil {
+		return nil, result, fmt.Errorf("failed to list snapshots: %w", err)
+	}
+	if len(ids) > 0 {
+		latest, err := LoadSnapshot(ctx, backup, ids[len(ids)-1])
+		if err != nil {
+			return nil, result, fmt.Errorf("failed to load latest snapshot: %w", err)
+		}
+		for _, entry := range latest.Entries {
+			previous[path.Join(entry.Location...)] = entry
+		}
+	}
+
+	manifest = &SnapshotManifest{ID: start.UTC().Format(SnapshotIDLayout)}
+	if len(ids) > 0 && manifest.ID <= ids[len(ids)-1] {
+		return nil, result, fmt.Errorf("snapshot %s is not newer than the latest one %s", manifest.ID, ids[len(ids)-1])
+	}
+
+	var (
+		mutex sync.Mutex
+		// Last error of the files that failed to be stored
+		failures = map[string]error{}
+	)
+	add := func(entry *SnapshotEntry) {
+		mutex.Lock()
+		defer mutex.Unlock()
+		manifest.Entries = append(manifest.Entries, entry)
+		delete(failures, path.Join(entry.Location...))
+	}
+	fail := func(location []string, err error) {
+		mutex.Lock()
+		defer mutex.Unlock()
+		failures[path.Join(location...)] = err
+	}
+
+	pool := newWorkerPool(ctx, syncCtx, result)
+	defer pool.Close()
+
+	var (
+		count   int64
+		listErr error
+	)
+	for entry, err := range src.Files(pool.ctx, syncCtx.ListOptions...) {
+		if err != nil {
+			listErr = err
+			break
+		}
+
+		if !syncCtx.Filter.Match(entry) {
+			continue
+		}
+		if syncCtx.MaxFiles > 0 && count >= syncCtx.MaxFiles {
+			break
+		}
+		count++
+		syncCtx.notify(Event{Kind: EventListed, Location: entry.Location(), Bytes: entry.Size()})
+
+		last, found := previous[path.Join(entry.Location()...)]
+		if found && sameModTime(last.ModTime, entry.ModTime()) && last.ListedSize == entry.Size() {
+			add(last)
+			pool.record(ActionSkip, entry.Location())
+			continue
+		}
+
+		ok := pool.Go(entry.Location(), func(ctx context.Context) (kind ActionKind, transferred int64, err error) {
+			hash := sha256.New()
+			transferred, err = copyFile(ctx, syncCtx, backup, src, ActionCreate,
+				snapshotContentLocation(manifest.ID, entry.Location()), entry.Location(), entry.ModTime(), hash)
+			if err != nil {
+				fail(entry.Location(), err)
+				return ActionCreate, transferred, fmt.Errorf("failed to snapshot: %s: %w", path.Join(entry.Location()...), err)
+			}
+
+			add(&SnapshotEntry{
+				Location:   entry.Location(),
+				Size:       transferred,
+				ListedSize: entry.Size(),
+				Sha256:     hex.EncodeToString(hash.Sum(nil)),
+				ModTime:    entry.ModTime(),
+				Snapshot:   manifest.ID,
+			})
+			return ActionCreate, transferred, nil
+		})
+		if !ok {
+			break
+		}
+	}
+
+	err = pool.Wait()
+	switch {
+	case err != nil:
+		return nil, result, err
+	case ctx.Err() != nil:
+		return nil, result, fmt.Errorf("failed to snapshot due to context error: %w", ctx.Err())
+	case listErr != nil:
+		return nil, result, fmt.Errorf("failed to list src files: %w: %w", ErrIncompleteListing, listErr)
+	}
+
+	// Failed files keep the contents of the latest snapshot, so restoring this one doesn't lose them
+	for location, err := range failures {
+		last, found := previous[location]
+		if found && !errors.Is(err, ErrSrcNotExist) {
+			manifest.Entries = append(manifest.Entries, last)
+		}
+	}
+	slices.SortFunc(manifest.Entries, func(a, b *SnapshotEntry) int {
+		return strings.Compare(path.Join(a.Location...), path.Join(b.Location...))
+	})
+	err = writeSnapshotManifest(ctx, backup, manifest, start)
+	if err != nil {
+		return nil, result, err
+	}
+	return manifest, result, nil
+}
+
+// Writes every file of the snapshot stored in backup into dst, verifying the contents checksum.
+// Files changed in dst since the snapshot are overwritten, files with corrupted contents are left untouched
+func Restore(ctx context.Context, dst, backup Filesystem, id string, options ...SyncOption) (result *SyncResult, err error) {
+	syncCtx := NewSyncCtx(options...)
+
+	start := time.Now()
+	result = &SyncResult{}
+	defer func() {
+		result.Duration = time.Since(start)
+		syncCtx.notify(Event{Kind: EventFinished, Err: err, Result: result})
+	}()
+
+	err = RequireCapabilities(dst, CapabilityWrite)
+	if err != nil {
+		return result, fmt.Errorf("invalid dst: %w", err)
+	}
+
+	manifest, err := LoadSnapshot(ctx, backup, id)
+	if err != nil {
+		return result, err
+	}
+
+	pool := newWorkerPool(ctx, syncCtx, result)
+	defer pool.Close()
+
+	for _, entry := range manifest.Entries {
+		listed := &SimpleFileEntry{
+			LocationValue: entry.Location,
+			ModTimeValue:  entry.ModTime,
+			SizeValue:     entry.Size,
+		}
+		if !syncCtx.Filter.Match(listed) {
+			continue
+		}
+		syncCtx.notify(Event{Kind: EventListed, Location: entry.Location, Bytes: entry.Size})
+
+		ok := pool.Go(entry.Location, func(ctx context.Context) (kind ActionKind, transferred int64, err error) {
+			// Corrupted contents are discarded before replacing the dst file
+			hash := sha256.New()
+			verify := func() (err error) {
+				if hex.EncodeToString(hash.Sum(nil)) != entry.Sha256 {
+					return fmt.Errorf("checksum mismatch: expecting %s", entry.Sha256)
+				}
+				return nil
+			}
+			transferred, err = copyFileVerified(ctx, syncCtx, dst, backup, ActionCreate,
+				entry.Location, snapshotContentLocation(entry.Snapshot, entry.Location), entry.ModTime, verify, hash)
+			// Contents missing from the backup can't be skipped like files removed from a sync src
+			if errors.Is(err, ErrSrcNotExist) {
+				err = fmt.Errorf("missing snapshot contents: %s", entry.Snapshot)
+			}
+			if err != nil {
+				return ActionCreate, transferred, fmt.Errorf("failed to restore: %s: %w", path.Join(entry.Location...), err)
+			}
+			return ActionCreate, transferred, nil
+		})
+		if !ok {
+			break
+		}
+	}
+
+	err = pool.Wait()
+	switch {
+	case err != nil:
+		return result, err
+	case ctx.Err() != nil:
+		return result, fmt.Errorf("failed to restore due to context error: %w", ctx.Err())
+	default:
+		return result, nil
+	}
+}
@@ -0,0 +1,318 @@
+// Copyright (C) 2025 ZedCloud Org.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package filesystem_test
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/fs"
+	"iter"
+	"os"
+	"path"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pluto-org-co/fsio/filesystem"
+	"github.com/pluto-org-co/fsio/filesystem/directory"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Snapshot(t *testing.T) {
+	if os.Getuid() == 0 {
+		t.Skip("Can't run this test as root")
+		return
+	}
+
+	assertions := assert.New(t)
+
+	ctx, cancel := context.WithTimeout(context.TODO(), time.Minute)
+	defer cancel()
+
+	src := directory.New(t.TempDir(), 0o777, 0o777)
+	backup := directory.New(t.TempDir(), 0o777, 0o777)
+
+	modTime := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	write := func(fs filesystem.Filesystem, location []string, contents string, modTime time.Time) (ok bool) {
+		_, err := fs.WriteFile(ctx, location, strings.NewReader(contents), modTime)
+		return assertions.Nil(err, "failed to write file")
+	}
+	read := func(fs filesystem.Filesystem, location []string) (contents string) {
+		file, err := fs.Open(ctx, location)
+		if !assertions.Nil(err, "failed to open file") {
+			return ""
+		}
+		defer file.Close()
+
+		raw, err := io.ReadAll(file)
+		assertions.Nil(err, "failed to read file")
+		return string(raw)
+	}
+
+	for _, name := range []string{"kept.txt", "updated.txt"} {
+		if !write(src, []string{"docs", name}, name, modTime) {
+			return
+		}
+	}
+
+	ids, err := filesystem.Snapshots(ctx, backup)
+	if !assertions.Nil(err, "failed to list snapshots") {
+		return
+	}
+	assertions.Empty(ids, "backup should start empty")
+
+	first, result, err := filesystem.Snapshot(ctx, backup, src)
+	if !assertions.Nil(err, "failed to snapshot") {
+		return
+	}
+	assertions.Equal(int64(2), result.Copied, "unexpected stored files")
+	assertions.Len(first.Entries, 2, "unexpected manifest entries")
+
+	// Snapshots are identified by the second they started at
+	time.Sleep(time.Second)
+
+	if !write(src, []string{"docs", "updated.txt"}, "new contents", modTime.Add(time.Hour)) {
+		return
+	}
+	if !write(src, []string{"docs", "created.txt"}, "created", modTime) {
+		return
+	}
+
+	second, result, err := filesystem.Snapshot(ctx, backup, src)
+	if !assertions.Nil(err, "failed to snapshot") {
+		return
+	}
+	assertions.Equal(int64(2), result.Copied, "only changed files should be stored")
+	assertions.Equal(int64(1), result.Skipped, "unchanged files should be skipped")
+
+	snapshots := map[string]string{}
+	for _, entry := range second.Entries {
+		snapshots[path.Join(entry.Location...)] = entry.Snapshot
+	}
+	assertions.Equal(map[string]string{
+		"docs/created.txt": second.ID,
+		"docs/kept.txt":    first.ID,
+		"docs/updated.txt": second.ID,
+	}, snapshots, "unexpected contents snapshots")
+
+	_, err = backup.Stat(ctx, []string{filesystem.SnapshotContentsRoot, second.ID, "docs", "kept.txt"})
+	assertions.ErrorIs(err, os.ErrNotExist, "unchanged contents should not be stored again")
+
+	ids, err = filesystem.Snapshots(ctx, backup)
+	if !assertions.Nil(err, "failed to list snapshots") {
+		return
+	}
+	assertions.Equal([]string{first.ID, second.ID}, ids, "unexpected snapshots")
+
+	t.Run("Load", func(t *testing.T) {
+		assertions := assert.New(t)
+
+		manifest, err := filesystem.LoadSnapshot(ctx, backup, second.ID)
+		if !assertions.Nil(err, "failed to load snapshot") {
+			return
+		}
+		assertions.Len(manifest.Entries, len(second.Entries), "unexpected manifest entries")
+
+		_, err = filesystem.LoadSnapshot(ctx, backup, "19700101T000000Z")
+		assertions.ErrorIs(err, filesystem.ErrSnapshotNotFound, "expecting missing snapshot")
+	})
+	t.Run("Restore", func(t *testing.T) {
+		assertions := assert.New(t)
+
+		dst := directory.New(t.TempDir(), 0o777, 0o777)
+		result, err := filesystem.Restore(ctx, dst, backup, first.ID)
+		if !assertions.Nil(err, "failed to restore snapshot") {
+			return
+		}
+		assertions.Equal(int64(2), result.Copied, "unexpected restored files")
+
+		assertions.Equal("kept.txt", read(dst, []string{"docs", "kept.txt"}), "unexpected kept contents")
+		assertions.Equal("updated.txt", read(dst, []string{"docs", "updated.txt"}), "previous contents should be restored")
+
+		_, err = dst.Stat(ctx, []string{"docs", "created.txt"})
+		assertions.ErrorIs(err, os.ErrNotExist, "later files should not be restored")
+
+		result, err = filesystem.Restore(ctx, dst, backup, second.ID)
+		if !assertions.Nil(err, "failed to restore snapshot") {
+			return
+		}
+		assertions.Equal(int64(3), result.Copied, "unexpected restored files")
+		assertions.Equal("new contents", read(dst, []string{"docs", "updated.txt"}), "latest contents should be restored")
+	})
+	t.Run("Corrupted", func(t *testing.T) {
+		assertions := assert.New(t)
+
+		if !write(backup, []string{filesystem.SnapshotContentsRoot, first.ID, "docs", "kept.txt"}, "corrupted", modTime) {
+			return
+		}
+
+		dst := directory.New(t.TempDir(), 0o777, 0o777)
+		result, err := filesystem.Restore(ctx, dst, backup, second.ID)
+		if !assertions.Nil(err, "failed to restore snapshot") {
+			return
+		}
+		assertions.Equal(int64(1), result.Failed, "corrupted contents should fail")
+		_, err = dst.Stat(ctx, []string{"docs", "kept.txt"})
+		assertions.ErrorIs(err, os.ErrNotExist, "corrupted contents should not be written")
+
+		if !write(dst, []string{"docs", "kept.txt"}, "local contents", modTime) {
+			return
+		}
+		result, err = filesystem.Restore(ctx, dst, backup, second.ID)
+		if !assertions.Nil(err, "failed to restore snapshot") {
+			return
+		}
+		assertions.Equal(int64(1), result.Failed, "corrupted contents should fail")
+		assertions.Equal("local contents", read(dst, []string{"docs", "kept.txt"}), "corrupted contents should not replace the dst file")
+	})
+}
+
+// Lists every file with an unknown size, like the documents exported by Google Drive
+type unknownSizes struct {
+	filesystem.Filesystem
+}
+
+func (u *unknownSizes) Files(ctx context.Context, options ...filesystem.ListOption) (seq iter.Seq2[filesystem.FileEntry, error]) {
+	return func(yield func(filesystem.FileEntry, error) bool) {
+		for entry, err := range u.Filesystem.Files(ctx, options...) {
+			if err != nil {
+				yield(nil, err)
+				return
+			}
+
+			entry = &filesystem.SimpleFileEntry{
+				LocationValue: entry.Location(),
+				ModTimeValue:  entry.ModTime(),
+				SizeValue:     -1,
+			}
+			if !yield(entry, nil) {
+				return
+			}
+		}
+	}
+}
+
+func Test_Snapshot_UnknownSize(t *testing.T) {
+	if os.Getuid() == 0 {
+		t.Skip("Can't run this test as root")
+		return
+	}
+
+	assertions := assert.New(t)
+
+	ctx, cancel := context.WithTimeout(context.TODO(), time.Minute)
+	defer cancel()
+
+	local := directory.New(t.TempDir(), 0o777, 0o777)
+	src := &unknownSizes{Filesystem: local}
+	backup := directory.New(t.TempDir(), 0o777, 0o777)
+
+	_, err := local.WriteFile(ctx, []string{"document"}, strings.NewReader("exported document"), time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC))
+	if !assertions.Nil(err, "failed to write file") {
+		return
+	}
+
+	_, result, err := filesystem.Snapshot(ctx, backup, src)
+	if !assertions.Nil(err, "failed to snapshot") {
+		return
+	}
+	assertions.Equal(int64(1), result.Copied, "unexpected stored files")
+
+	// Snapshots are identified by the second they started at
+	time.Sleep(time.Second)
+
+	manifest, result, err := filesystem.Snapshot(ctx, backup, src)
+	if !assertions.Nil(err, "failed to snapshot") {
+		return
+	}
+	assertions.Zero(result.Copied, "files with unknown sizes should not be stored again")
+	assertions.Equal(int64(1), result.Skipped, "unchanged files should be skipped")
+	if assertions.Len(manifest.Entries, 1, "unexpected manifest entries") {
+		assertions.Equal(int64(len("exported document")), manifest.Entries[0].Size, "stored size should be kept")
+	}
+}
+
+// Fails opening the file at the location
+type deniedOpen struct {
+	filesystem.Filesystem
+	denied string
+}
+
+func (d *deniedOpen) Open(ctx context.Context, location []string) (rc io.ReadCloser, err error) {
+	if path.Join(location...) == d.denied {
+		return nil, fmt.Errorf("failed to open: %w", fs.ErrPermission)
+	}
+	return d.Filesystem.Open(ctx, location)
+}
+
+func Test_Snapshot_Failed(t *testing.T) {
+	if os.Getuid() == 0 {
+		t.Skip("Can't run this test as root")
+		return
+	}
+
+	assertions := assert.New(t)
+
+	ctx, cancel := context.WithTimeout(context.TODO(), time.Minute)
+	defer cancel()
+
+	local := directory.New(t.TempDir(), 0o777, 0o777)
+	backup := directory.New(t.TempDir(), 0o777, 0o777)
+
+	modTime := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	for _, name := range []string{"kept.txt", "failed.txt"} {
+		_, err := local.WriteFile(ctx, []string{name}, strings.NewReader(name), modTime)
+		if !assertions.Nil(err, "failed to write file") {
+			return
+		}
+	}
+
+	first, _, err := filesystem.Snapshot(ctx, backup, local)
+	if !assertions.Nil(err, "failed to snapshot") {
+		return
+	}
+
+	// Snapshots are identified by the second they started at
+	time.Sleep(time.Second)
+
+	_, err = local.WriteFile(ctx, []string{"failed.txt"}, strings.NewReader("changed contents"), modTime.Add(time.Hour))
+	if !assertions.Nil(err, "failed to write file") {
+		return
+	}
+
+	second, result, err := filesystem.Snapshot(ctx, backup, &deniedOpen{Filesystem: local, denied: "failed.txt"})
+	if !assertions.Nil(err, "failed to snapshot") {
+		return
+	}
+	assertions.Equal(int64(1), result.Failed, "unexpected failed files")
+
+	snapshots := map[string]string{}
+	for _, entry := range second.Entries {
+		snapshots[path.Join(entry.Location...)] = entry.Snapshot
+	}
+	assertions.Equal(map[string]string{
+		"failed.txt": first.ID,
+		"kept.txt":   first.ID,
+	}, snapshots, "failed files should keep the previous entry")
+
+	dst := directory.New(t.TempDir(), 0o777, 0o777)
+	result, err = filesystem.Restore(ctx, dst, backup, second.ID)
+	if !assertions.Nil(err, "failed to restore snapshot") {
+		return
+	}
+	assertions.Equal(int64(2), result.Copied, "every file should be restored")
+}
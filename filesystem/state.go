@@ -0,0 +1,96 @@
+// Copyright (C) 2025 ZedCloud Org.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package filesystem
+
+import (
+	"fmt"
+	"time"
+)
+
+// Version of a src file as listed when it was last synchronized
+type StateRecord struct {
+	ModTime time.Time `json:"mod-time,omitzero"`
+	// Negative when unknown
+	Size int64 `json:"size"`
+}
+
+func recordOf(entry FileEntry) (record StateRecord) {
+	return StateRecord{
+		ModTime: entry.ModTime(),
+		Size:    entry.Size(),
+	}
+}
+
+// Reports if the listed src file is still the synchronized version
+func (r StateRecord) Matches(entry FileEntry) (ok bool) {
+	return sameModTime(r.ModTime, entry.ModTime()) && r.Size == entry.Size()
+}
+
+// Remembers the src files synchronized by previous syncs, so the unchanged ones are skipped
+// without comparing them with dst. Implementations must be safe for concurrent use
+type SyncState interface {
+	// Returns the version recorded for the location
+	Get(location []string) (record StateRecord, found bool)
+	// Records the version of the src file found in dst
+	Put(location []string, record StateRecord) (err error)
+	// Forgets the location
+	Delete(location []string) (err error)
+	// Returns every recorded location
+	Locations() (locations [][]string)
+}
+
+// Reports if the state recorded the listed src file as synchronized
+func (s *SyncCtx) synchronized(entry FileEntry) (ok bool) {
+	if s.State == nil {
+		return false
+	}
+	record, found := s.State.Get(entry.Location())
+	return found && record.Matches(entry)
+}
+
+// Records the outcome of the action in the state
+func (s *SyncCtx) remember(kind ActionKind, entry FileEntry, from []string) (err error) {
+	if s.State == nil {
+		return nil
+	}
+
+	switch kind {
+	case ActionCreate, ActionUpdate, ActionSkip:
+		err = s.State.Put(entry.Location(), recordOf(entry))
+	case ActionMove:
+		err = s.State.Delete(from)
+		if err == nil {
+			err = s.State.Put(entry.Location(), recordOf(entry))
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("failed to record sync state: %w", err)
+	}
+	return nil
+}
+
+// Forgets the removed dst location
+func (s *SyncCtx) forget(location []string) (err error) {
+	if s.State == nil {
+		return nil
+	}
+
+	err = s.State.Delete(location)
+	if err != nil {
+		return fmt.Errorf("failed to record sync state: %w", err)
+	}
+	return nil
+}
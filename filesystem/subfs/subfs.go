@@ -0,0 +1,212 @@
+// Copyright (C) 2025 ZedCloud Org.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package subfs
+
+import (
+	"context"
+	"io"
+	"iter"
+	"slices"
+	"time"
+
+	"github.com/pluto-org-co/fsio/filesystem"
+)
+
+// This FS scopes every operation of the underlying Filesystem under a location prefix.
+// Locations are passed relative to the prefix and listed entries have the prefix stripped.
+type Sub struct {
+	fs     filesystem.Filesystem
+	prefix []string
+}
+
+var (
+	_ filesystem.Filesystem           = (*Sub)(nil)
+	_ filesystem.CapabilitiesReporter = (*Sub)(nil)
+	_ filesystem.RangeReader          = (*Sub)(nil)
+	_ filesystem.Creator              = (*Sub)(nil)
+)
+
+func New(fs filesystem.Filesystem, prefix ...string) (s *Sub) {
+	return &Sub{
+		fs:     fs,
+		prefix: slices.Clone(prefix),
+	}
+}
+
+// Location in the underlying filesystem. Locations are validated before being prefixed,
+// otherwise an empty location would refer to the whole prefix
+func (s *Sub) join(location []string) (fullLocation []string, err error) {
+	err = filesystem.ValidateLocation(location)
+	if err != nil {
+		return nil, err
+	}
+	return s.prefixed(location), nil
+}
+
+// Prefixes the passed location, which may be an empty listing prefix
+func (s *Sub) prefixed(location []string) (fullLocation []string) {
+	fullLocation = make([]string, 0, len(s.prefix)+len(location))
+	fullLocation = append(fullLocation, s.prefix...)
+	fullLocation = append(fullLocation, location...)
+	return fullLocation
+}
+
+// Location relative to the prefix
+func (s *Sub) strip(fullLocation []string) (location []string) {
+	if len(fullLocation) < len(s.prefix) || !slices.Equal(fullLocation[:len(s.prefix)], s.prefix) {
+		return fullLocation
+	}
+	return slices.Clone(fullLocation[len(s.prefix):])
+}
+
+func (s *Sub) Capabilities() (caps filesystem.Capability) {
+	return filesystem.Capabilities(s.fs)
+}
+
+func (s *Sub) ChecksumTime(ctx context.Context, location []string) (checksum string, err error) {
+	fullLocation, err := s.join(location)
+	if err != nil {
+		return "", err
+	}
+	return s.fs.ChecksumTime(ctx, fullLocation)
+}
+
+func (s *Sub) ChecksumSha256(ctx context.Context, location []string) (checksum string, err error) {
+	fullLocation, err := s.join(location)
+	if err != nil {
+		return "", err
+	}
+	return s.fs.ChecksumSha256(ctx, fullLocation)
+}
+
+func (s *Sub) Stat(ctx context.Context, location []string) (info *filesystem.FileInfo, err error) {
+	fullLocation, err := s.join(location)
+	if err != nil {
+		return nil, err
+	}
+
+	info, err = s.fs.Stat(ctx, fullLocation)
+	if err != nil {
+		return nil, err
+	}
+
+	info.Location = s.strip(info.Location)
+	return info, nil
+}
+
+func (s *Sub) Files(ctx context.Context, options ...filesystem.ListOption) (seq iter.Seq2[filesystem.FileEntry, error]) {
+	listCtx := filesystem.NewListCtx(options...)
+
+	return func(yield func(filesystem.FileEntry, error) bool) {
+		entries := s.fs.Files(ctx,
+			filesystem.WithListOptionPrefix(s.prefixed(listCtx.Prefix)...),
+			filesystem.WithListOptionRecursive(listCtx.Recursive),
+		)
+		for entry, err := range entries {
+			if err != nil {
+				yield(nil, err)
+				return
+			}
+
+			subEntry := &filesystem.SimpleFileEntry{
+				LocationValue:    s.strip(entry.Location()),
+				ModTimeValue:     entry.ModTime(),
+				SizeValue:        entry.Size(),
+				ContentTypeValue: entry.ContentType(),
+				SysValue:         entry.Sys(),
+			}
+			if !yield(subEntry, nil) {
+				return
+			}
+		}
+	}
+}
+
+func (s *Sub) Open(ctx context.Context, location []string) (rc io.ReadCloser, err error) {
+	fullLocation, err := s.join(location)
+	if err != nil {
+		return nil, err
+	}
+	return s.fs.Open(ctx, fullLocation)
+}
+
+func (s *Sub) OpenRange(ctx context.Context, location []string, offset, length int64) (rc io.ReadCloser, err error) {
+	fullLocation, err := s.join(location)
+	if err != nil {
+		return nil, err
+	}
+	return filesystem.OpenRange(ctx, s.fs, fullLocation, offset, length)
+}
+
+func (s *Sub) WriteFile(ctx context.Context, location []string, src io.Reader, modTime time.Time) (finalLocation []string, err error) {
+	fullLocation, err := s.join(location)
+	if err != nil {
+		return nil, err
+	}
+
+	finalLocation, err = s.fs.WriteFile(ctx, fullLocation, src, modTime)
+	if err != nil {
+		return nil, err
+	}
+	return s.strip(finalLocation), nil
+}
+
+type subWriter struct {
+	filesystem.FileWriter
+	sub *Sub
+}
+
+func (w *subWriter) Location() (location []string) {
+	return w.sub.strip(w.FileWriter.Location())
+}
+
+func (s *Sub) Create(ctx context.Context, location []string, modTime time.Time) (w filesystem.FileWriter, err error) {
+	fullLocation, err := s.join(location)
+	if err != nil {
+		return nil, err
+	}
+
+	w, err = filesystem.Create(ctx, s.fs, fullLocation, modTime)
+	if err != nil {
+		return nil, err
+	}
+	return &subWriter{FileWriter: w, sub: s}, nil
+}
+
+func (s *Sub) RemoveAll(ctx context.Context, location []string) (err error) {
+	fullLocation, err := s.join(location)
+	if err != nil {
+		return err
+	}
+	return s.fs.RemoveAll(ctx, fullLocation)
+}
+
+func (s *Sub) Move(ctx context.Context, oldLocation, newLocation []string) (finalLocation []string, err error) {
+	fullOldLocation, err := s.join(oldLocation)
+	if err != nil {
+		return nil, err
+	}
+	fullNewLocation, err := s.join(newLocation)
+	if err != nil {
+		return nil, err
+	}
+
+	finalLocation, err = s.fs.Move(ctx, fullOldLocation, fullNewLocation)
+	if err != nil {
+		return nil, err
+	}
+	return s.strip(finalLocation), nil
+}
@@ -0,0 +1,84 @@
+// Copyright (C) 2025 ZedCloud Org.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package subfs_test
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/pluto-org-co/fsio/filesystem"
+	"github.com/pluto-org-co/fsio/filesystem/directory"
+	"github.com/pluto-org-co/fsio/filesystem/randomfs"
+	"github.com/pluto-org-co/fsio/filesystem/subfs"
+	"github.com/pluto-org-co/fsio/filesystem/testsuite"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Sub(t *testing.T) {
+	assertions := assert.New(t)
+
+	tempDir, err := os.MkdirTemp("", "*")
+	if !assertions.Nil(err, "failed to create temp") {
+		return
+	}
+	defer os.RemoveAll(tempDir)
+	localRoot := directory.New(tempDir, 0o777, 0o777)
+
+	// Contents outside the prefix must not be visible
+	outside := randomfs.New(testsuite.GenerateLocations(10), 1024)
+
+	ctx, cancel := context.WithTimeout(context.TODO(), time.Minute)
+	defer cancel()
+
+	err = filesystem.Copy(ctx, subfs.New(localRoot, "outside"), outside)
+	if !assertions.Nil(err, "failed to copy outside contents") {
+		return
+	}
+
+	subRoot := subfs.New(localRoot, "workspaces", "first")
+
+	t.Run("Testsuite", testsuite.TestFilesystem(t, subRoot))
+	t.Run("Scoped", func(t *testing.T) {
+		assertions := assert.New(t)
+
+		ctx, cancel := context.WithTimeout(context.TODO(), time.Minute)
+		defer cancel()
+
+		for entry, err := range subRoot.Files(ctx) {
+			if !assertions.Nil(err, "failed to list files") {
+				return
+			}
+
+			_, err = localRoot.Stat(ctx, append([]string{"workspaces", "first"}, entry.Location()...))
+			if !assertions.Nil(err, "listed file should exist under the prefix") {
+				return
+			}
+		}
+
+		for entry, err := range subfs.New(localRoot, "outside").Files(ctx) {
+			if !assertions.Nil(err, "failed to list files") {
+				return
+			}
+
+			_, err = subRoot.Stat(ctx, entry.Location())
+			if !assertions.ErrorIs(err, os.ErrNotExist, "files outside the prefix should not be reachable") {
+				return
+			}
+		}
+	})
+}
@@ -17,15 +17,72 @@ package filesystem
 
 import (
 	"context"
-	"errors"
 	"fmt"
-	"log"
+	"io"
+	"math/rand/v2"
 	"path"
-	"sync"
+	"time"
+
+	"github.com/pluto-org-co/fsio/ioutils"
+)
+
+const (
+	// Share of the dst files mirrors may remove unless configured
+	DefaultMaxDeletePercent = 50
+	// Attempts repeated for each file failing with a retryable error unless configured
+	DefaultRetries = 3
+	// Wait before the first repeated attempt, doubled for every following one
+	DefaultRetryBackoff = time.Second
+	// Upper bound of the wait between attempts
+	DefaultRetryMaxBackoff = 30 * time.Second
+)
+
+// Modification time written into the destination files
+type ModTimePolicy int
+
+const (
+	// Keep the modification time reported by the source listing
+	ModTimePreserve ModTimePolicy = iota
+	// Stamp every written file with the time the sync started
+	ModTimeSyncStart
 )
 
 type SyncCtx struct {
+	// Maximum number of listed files processed. Zero or negative processes every file
 	MaxFiles int64
+	// Options used for listing the source filesystem
+	ListOptions []ListOption
+	// Number of files transferred concurrently
+	Workers int
+	// Stop at the first failed file instead of logging it and continuing with the rest
+	FailFast bool
+	// Modification time written into the destination files
+	ModTime ModTimePolicy
+	// Remove the destination files missing from the source after copying
+	Mirror bool
+	// Maximum percentage of the listed destination files a mirror may remove
+	MaxDeletePercent float64
+	// Receivers of the sync progress
+	Observers []Observer
+	// How unchanged files are detected
+	Compare CompareStrategy
+	// Files excluded by the filter are ignored in both listings. Nil includes every file
+	Filter *Filter
+	// Attempts repeated for each file failing with an error classified as ErrorActionRetry
+	Retries int
+	// Wait before the first repeated attempt, doubled for every following one up to RetryMaxBackoff
+	RetryBackoff    time.Duration
+	RetryMaxBackoff time.Duration
+	// Files recorded as synchronized by previous syncs are skipped without comparing them. Nil compares every file
+	State SyncState
+	// Limits the bytes read from src by every worker. Nil is unlimited.
+	// Backends buffering the written contents, like S3 multipart uploads, still send them in bursts;
+	// limit their HTTP client with ioutils.RateLimitTransport for capping the network
+	RateLimiter *ioutils.RateLimiter
+	// How BiSync resolves the files modified on both sides
+	Conflict ConflictPolicy
+
+	observers *observers
 }
 
 type SyncOption func(ctx *SyncCtx) (err error)
@@ -37,134 +94,323 @@ func WithSyncOptionMaxFiles(maxFiles int64) (option SyncOption) {
 	}
 }
 
-// Same as Copy but doesn't stop on errors
-func Sync(ctx context.Context, dst, src Filesystem, options ...SyncOption) (err error) {
-	var syncCtx = &SyncCtx{
-		MaxFiles: -1,
+// Restricts the synchronized files to the ones listed with the passed options
+func WithSyncOptionListOptions(options ...ListOption) (option SyncOption) {
+	return func(ctx *SyncCtx) (err error) {
+		ctx.ListOptions = options
+		return nil
+	}
+}
+
+// Values lower than one are replaced by a single worker
+func WithSyncOptionWorkers(workers int) (option SyncOption) {
+	return func(ctx *SyncCtx) (err error) {
+		ctx.Workers = max(workers, 1)
+		return nil
+	}
+}
+
+func WithSyncOptionFailFast(failFast bool) (option SyncOption) {
+	return func(ctx *SyncCtx) (err error) {
+		ctx.FailFast = failFast
+		return nil
+	}
+}
+
+func WithSyncOptionModTime(policy ModTimePolicy) (option SyncOption) {
+	return func(ctx *SyncCtx) (err error) {
+		ctx.ModTime = policy
+		return nil
+	}
+}
+
+// Removes the dst files missing from the src listing once every file was copied.
+// Files renamed in src are moved when dst supports it.
+// Deletions are refused when the src listing was incomplete or limited by MaxFiles
+func WithSyncOptionMirror(mirror bool) (option SyncOption) {
+	return func(ctx *SyncCtx) (err error) {
+		ctx.Mirror = mirror
+		return nil
+	}
+}
+
+// Refuses mirror deletions above the passed percentage of the listed dst files
+func WithSyncOptionMaxDeletePercent(percent float64) (option SyncOption) {
+	return func(ctx *SyncCtx) (err error) {
+		ctx.MaxDeletePercent = percent
+		return nil
+	}
+}
+
+func WithSyncOptionCompare(strategy CompareStrategy) (option SyncOption) {
+	return func(ctx *SyncCtx) (err error) {
+		ctx.Compare = strategy
+		return nil
+	}
+}
+
+// Only synchronizes the files passing the filter. Mirrors never remove the dst files excluded by the filter
+func WithSyncOptionFilter(filter *Filter) (option SyncOption) {
+	return func(ctx *SyncCtx) (err error) {
+		ctx.Filter = filter
+		return nil
+	}
+}
+
+// Values lower than zero disable the retries
+func WithSyncOptionRetries(retries int) (option SyncOption) {
+	return func(ctx *SyncCtx) (err error) {
+		ctx.Retries = max(retries, 0)
+		return nil
+	}
+}
+
+// Waits between attempts grow exponentially from backoff up to maxBackoff, with a random jitter
+func WithSyncOptionRetryBackoff(backoff, maxBackoff time.Duration) (option SyncOption) {
+	return func(ctx *SyncCtx) (err error) {
+		ctx.RetryBackoff = backoff
+		ctx.RetryMaxBackoff = max(backoff, maxBackoff)
+		return nil
+	}
+}
+
+// Skips the src files left unchanged since the state recorded them, and records every synchronized file.
+// Files modified in dst by other means than the sync aren't detected while their src is unchanged
+func WithSyncOptionState(state SyncState) (option SyncOption) {
+	return func(ctx *SyncCtx) (err error) {
+		ctx.State = state
+		return nil
+	}
+}
+
+// Shares the bandwidth of the limiter between every transfer. The limiter may also be shared with other syncs
+func WithSyncOptionRateLimiter(limiter *ioutils.RateLimiter) (option SyncOption) {
+	return func(ctx *SyncCtx) (err error) {
+		ctx.RateLimiter = limiter
+		return nil
+	}
+}
+
+// Reports the progress of the sync to the observer. May be passed multiple times
+func WithSyncOptionObserver(observer Observer) (option SyncOption) {
+	return func(ctx *SyncCtx) (err error) {
+		ctx.Observers = append(ctx.Observers, observer)
+		return nil
+	}
+}
+
+// Prepares the sync context from the passed options.
+// By default every file is synchronized by a single worker, preserving the modification times, skipping the files
+// with the same modification time, retrying transient failures and continuing after failed files.
+// Mirrors may remove up to half of the dst files
+func NewSyncCtx(options ...SyncOption) (syncCtx *SyncCtx) {
+	syncCtx = &SyncCtx{
+		Workers:          1,
+		MaxDeletePercent: DefaultMaxDeletePercent,
+		Compare:          CompareModTime,
+		Retries:          DefaultRetries,
+		RetryBackoff:     DefaultRetryBackoff,
+		RetryMaxBackoff:  DefaultRetryMaxBackoff,
 	}
 	for _, option := range options {
 		option(syncCtx)
 	}
+	syncCtx.observers = &observers{observers: syncCtx.Observers}
+	return syncCtx
+}
 
-	var count int64
-	for entry := range src.Files(ctx) {
-		if syncCtx.MaxFiles > 0 && count >= syncCtx.MaxFiles {
-			return nil
+func (s *SyncCtx) notify(event Event) {
+	s.observers.notify(event)
+}
+
+// Modification time written into dst for the src entry
+func (s *SyncCtx) modTimeOf(entry FileEntry, start time.Time) (modTime time.Time) {
+	if s.ModTime == ModTimeSyncStart {
+		return start
+	}
+	return entry.ModTime()
+}
+
+// Wait before repeating the attempt. Grows exponentially with a random jitter of up to half the wait
+func (s *SyncCtx) backoff(attempt int) (wait time.Duration) {
+	wait = s.RetryBackoff
+	for range attempt {
+		if wait >= s.RetryMaxBackoff {
+			break
 		}
-		count++
+		wait *= 2
+	}
+	wait = min(wait, s.RetryMaxBackoff)
+	if wait <= 0 {
+		return 0
+	}
+	return wait/2 + rand.N(wait/2+1)
+}
 
-		select {
-		case <-ctx.Done():
-			err = ctx.Err()
-			if err != nil {
-				return fmt.Errorf("failed to sync do to context error: %w", err)
-			}
-			return nil
-		default:
-			err := func() (err error) {
-				srcChecksum, _ := src.ChecksumTime(ctx, entry.Location())
-				dstChecksum, _ := dst.ChecksumTime(ctx, entry.Location())
-
-				if srcChecksum != "" && srcChecksum == dstChecksum {
-					return nil
-				}
-
-				srcFile, err := src.Open(ctx, entry.Location())
-				if err != nil {
-					return fmt.Errorf("failed to open src file: %w", err)
-				}
-				defer srcFile.Close()
-
-				_, err = dst.WriteFile(ctx, entry.Location(), srcFile, entry.ModTime())
-				if err != nil {
-					return fmt.Errorf("failed to write dst file: %w", err)
-				}
-
-				return nil
-			}()
-			if err != nil {
-				err = fmt.Errorf("failed to sync: %s: %w", entry, err)
-				log.Println(err)
-				if errors.Is(err, context.DeadlineExceeded) {
-					return err
-				}
-			}
+// Writes the src file into dst. Reports the bytes read from src
+func transferFile(ctx context.Context, syncCtx *SyncCtx, dst, src Filesystem, kind ActionKind, location []string, modTime time.Time) (transferred int64, err error) {
+	return copyFile(ctx, syncCtx, dst, src, kind, location, location, modTime)
+}
+
+// Writes the src file found at srcLocation into dst at dstLocation. Reports the bytes read from src.
+// The contents are also written into the tees, like hashes
+func copyFile(ctx context.Context, syncCtx *SyncCtx, dst, src Filesystem, kind ActionKind, dstLocation, srcLocation []string, modTime time.Time, tees ...io.Writer) (transferred int64, err error) {
+	return copyFileVerified(ctx, syncCtx, dst, src, kind, dstLocation, srcLocation, modTime, nil, tees...)
+}
+
+// Same as copyFile, but the dst file is only committed when verify succeeds once every byte was copied.
+// Otherwise the written contents are discarded and the error of verify returned
+func copyFileVerified(ctx context.Context, syncCtx *SyncCtx, dst, src Filesystem, kind ActionKind, dstLocation, srcLocation []string, modTime time.Time, verify func() (err error), tees ...io.Writer) (transferred int64, err error) {
+	syncCtx.notify(Event{Kind: EventStarted, Action: kind, Location: dstLocation})
+
+	srcFile, err := src.Open(ctx, srcLocation)
+	if err != nil {
+		return 0, fmt.Errorf("failed to open src file: %w", srcError(err))
+	}
+	defer srcFile.Close()
+
+	var reader io.Reader = srcFile
+	if syncCtx.RateLimiter != nil {
+		reader = ioutils.NewRateLimitReader(ctx, reader, syncCtx.RateLimiter)
+	}
+
+	counter := ioutils.NewCountWriterFunc(io.Discard, func(count int64) {
+		syncCtx.notify(Event{Kind: EventProgress, Action: kind, Location: dstLocation, Bytes: count})
+	})
+
+	dstFile, err := Create(ctx, dst, dstLocation, modTime)
+	if err != nil {
+		return 0, fmt.Errorf("failed to create dst file: %w", err)
+	}
+
+	_, err = ioutils.CopyContext(ctx, dstFile, io.TeeReader(reader, io.MultiWriter(append(tees, counter)...)), ioutils.DefaultBufferSize)
+	if err != nil {
+		dstFile.Abort()
+		return counter.Count(), fmt.Errorf("failed to write dst file: %w", err)
+	}
+
+	if verify != nil {
+		err = verify()
+		if err != nil {
+			dstFile.Abort()
+			return counter.Count(), err
 		}
+	}
 
+	err = dstFile.Close()
+	if err != nil {
+		return counter.Count(), fmt.Errorf("failed to write dst file: %w", err)
 	}
-	return nil
+	return counter.Count(), nil
 }
 
-func SyncWorkers(workersNumber int, ctx context.Context, dst, src Filesystem, options ...SyncOption) (err error) {
-	var syncCtx = &SyncCtx{
-		MaxFiles: 0,
+// Writes the src file into dst unless dst already holds the same version
+func syncFile(ctx context.Context, syncCtx *SyncCtx, dst, src Filesystem, location []string, modTime time.Time) (kind ActionKind, transferred int64, err error) {
+	srcInfo, _ := src.Stat(ctx, location)
+	dstInfo, _ := dst.Stat(ctx, location)
+	same, err := syncCtx.unchanged(ctx, dst, src, srcInfo, dstInfo)
+	if err != nil {
+		return "", 0, fmt.Errorf("failed to compare files: %w", err)
 	}
-	for _, option := range options {
-		option(syncCtx)
+	if same {
+		return ActionSkip, 0, nil
+	}
+
+	kind = ActionUpdate
+	if dstInfo == nil {
+		kind = ActionCreate
 	}
 
-	var workers = make(chan struct{}, workersNumber)
-	for range workersNumber {
-		workers <- struct{}{}
+	transferred, err = transferFile(ctx, syncCtx, dst, src, kind, location, modTime)
+	return kind, transferred, err
+}
+
+// Writes every src file missing or outdated in dst.
+// When mirroring, the dst files missing from src are moved or removed following the Plan of the sync.
+// Src files removed after being listed are skipped. Other failures are recorded in the result, or stop the sync when FailFast is set.
+// Context errors always stop the sync. The result is returned even when the sync was stopped
+func Sync(ctx context.Context, dst, src Filesystem, options ...SyncOption) (result *SyncResult, err error) {
+	syncCtx := NewSyncCtx(options...)
+
+	start := time.Now()
+	result = &SyncResult{}
+	defer func() {
+		result.Duration = time.Since(start)
+		syncCtx.notify(Event{Kind: EventFinished, Err: err, Result: result})
+	}()
+
+	if syncCtx.Mirror {
+		err = RequireCapabilities(dst, CapabilityRemove)
+		if err != nil {
+			return result, fmt.Errorf("invalid mirror dst: %w", err)
+		}
+
+		plan, planErr := syncCtx.plan(ctx, dst, src, start)
+		err = applyPlan(ctx, dst, src, syncCtx, plan, result)
+		if err != nil {
+			return result, err
+		}
+		return result, planErr
 	}
-	defer close(workers)
 
-	errorsCh := make(chan error, workersNumber)
+	pool := newWorkerPool(ctx, syncCtx, result)
+	defer pool.Close()
 
-	var wg sync.WaitGroup
-	defer wg.Wait()
+	var (
+		count   int64
+		listErr error
+	)
+	for entry, err := range src.Files(pool.ctx, syncCtx.ListOptions...) {
+		if err != nil {
+			listErr = err
+			break
+		}
 
-	var count int64
-	for entry := range src.Files(ctx) {
+		if !syncCtx.Filter.Match(entry) {
+			continue
+		}
 		if syncCtx.MaxFiles > 0 && count >= syncCtx.MaxFiles {
-			return nil
+			break
 		}
 		count++
+		syncCtx.notify(Event{Kind: EventListed, Location: entry.Location(), Bytes: entry.Size()})
+
+		if syncCtx.synchronized(entry) {
+			pool.record(ActionSkip, entry.Location())
+			continue
+		}
 
-		select {
-		case err := <-errorsCh:
-			if errors.Is(err, context.DeadlineExceeded) {
-				return err
+		modTime := syncCtx.modTimeOf(entry, start)
+		ok := pool.Go(entry.Location(), func(ctx context.Context) (kind ActionKind, transferred int64, err error) {
+			kind, transferred, err = syncFile(ctx, syncCtx, dst, src, entry.Location(), modTime)
+			if err == nil {
+				err = syncCtx.remember(kind, entry, nil)
 			}
-			log.Println(err)
-		case <-ctx.Done():
-			err = ctx.Err()
 			if err != nil {
-				return fmt.Errorf("failed to sync do to context error: %w", err)
+				return kind, transferred, fmt.Errorf("failed to sync: %s: %w", path.Join(entry.Location()...), err)
 			}
-			return nil
-		case <-workers:
-			wg.Go(func() {
-				defer func() { workers <- struct{}{} }()
-
-				err := func() (err error) {
-					srcChecksum, _ := src.ChecksumTime(ctx, entry.Location())
-					dstChecksum, _ := dst.ChecksumTime(ctx, entry.Location())
-
-					if srcChecksum != "" && srcChecksum == dstChecksum {
-						log.Println("SKIP:", path.Join(entry.Location()...))
-						return nil
-					}
-
-					srcFile, err := src.Open(ctx, entry.Location())
-					if err != nil {
-						return fmt.Errorf("failed to open src file: %w", err)
-					}
-					defer srcFile.Close()
-
-					_, err = dst.WriteFile(ctx, entry.Location(), srcFile, entry.ModTime())
-					if err != nil {
-						return fmt.Errorf("failed to write dst file: %w", err)
-					}
-
-					return nil
-				}()
-				if err != nil {
-					errorsCh <- fmt.Errorf("failed to sync: %s: %w", entry, err)
-				}
-			})
+			return kind, transferred, nil
+		})
+		if !ok {
+			break
 		}
 	}
 
-	return nil
+	err = pool.Wait()
+	switch {
+	case err != nil:
+		return result, err
+	case ctx.Err() != nil:
+		return result, fmt.Errorf("failed to sync due to context error: %w", ctx.Err())
+	case listErr != nil:
+		return result, fmt.Errorf("failed to list src files: %w: %w", ErrIncompleteListing, listErr)
+	default:
+		return result, nil
+	}
+}
+
+// Same as Sync using the passed number of workers
+func SyncWorkers(workersNumber int, ctx context.Context, dst, src Filesystem, options ...SyncOption) (result *SyncResult, err error) {
+	options = append([]SyncOption{WithSyncOptionWorkers(workersNumber)}, options...)
+	return Sync(ctx, dst, src, options...)
 }
@@ -242,7 +242,11 @@ func Test_SyncDrive(t *testing.T) {
 				defer cancel()
 
 				var count int
-				for entry := range dst.Files(ctx) {
+				for entry, err := range dst.Files(ctx) {
+					if !assertions.Nil(err, "failed to list files") {
+						return
+					}
+
 					count++
 					t.Logf("- Location: %s", entry.Location())
 				}
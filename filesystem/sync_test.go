@@ -17,14 +17,24 @@ package filesystem_test
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"iter"
 	"os"
+	"path"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
 	"github.com/pluto-org-co/fsio/filesystem"
 	"github.com/pluto-org-co/fsio/filesystem/directory"
+	"github.com/pluto-org-co/fsio/filesystem/journal"
 	"github.com/pluto-org-co/fsio/filesystem/randomfs"
 	"github.com/pluto-org-co/fsio/filesystem/testsuite"
+	"github.com/pluto-org-co/fsio/ioutils"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -50,7 +60,7 @@ func Test_Sync(t *testing.T) {
 
 		ctx, cancel := context.WithTimeout(context.TODO(), time.Minute)
 		defer cancel()
-		err = filesystem.Sync(ctx, src, randomSrc)
+		_, err = filesystem.Sync(ctx, src, randomSrc)
 		if !assertions.Nil(err, "failed to copy files") {
 			return
 		}
@@ -70,7 +80,7 @@ func Test_Sync(t *testing.T) {
 			defer cancel()
 
 			now := time.Now()
-			err = filesystem.Sync(ctx, dst, src)
+			_, err = filesystem.Sync(ctx, dst, src)
 			if !assertions.Nil(err, "failed to copy files") {
 				return
 			}
@@ -82,7 +92,7 @@ func Test_Sync(t *testing.T) {
 				defer cancel()
 
 				now := time.Now()
-				err = filesystem.Sync(ctx, dst, src)
+				_, err = filesystem.Sync(ctx, dst, src)
 				if !assertions.Nil(err, "failed to copy files") {
 					return
 				}
@@ -108,7 +118,7 @@ func Test_Sync(t *testing.T) {
 			defer cancel()
 
 			now := time.Now()
-			err = filesystem.SyncWorkers(100, ctx, dst, src)
+			_, err = filesystem.SyncWorkers(100, ctx, dst, src)
 			if !assertions.Nil(err, "failed to copy files") {
 				return
 			}
@@ -121,12 +131,16 @@ func Test_Sync(t *testing.T) {
 				defer cancel()
 
 				now := time.Now()
-				err = filesystem.SyncWorkers(100, ctx, dst, src)
+				result, err := filesystem.SyncWorkers(100, ctx, dst, src)
 				if !assertions.Nil(err, "failed to copy files") {
 					return
 				}
 				secondTook := time.Since(now)
 
+				assertions.Zero(result.Copied, "no file should be copied again")
+				assertions.Equal(int64(100), result.Skipped, "every file should be skipped")
+				assertions.Zero(result.Bytes, "no bytes should be transferred")
+
 				if !assertions.Less(secondTook, firstTook, "second sync should be faster") {
 					return
 				}
@@ -134,3 +148,643 @@ func Test_Sync(t *testing.T) {
 		})
 	})
 }
+
+// Fails the listing after the first file
+type brokenListing struct {
+	filesystem.Filesystem
+}
+
+func (b *brokenListing) Files(ctx context.Context, options ...filesystem.ListOption) (seq iter.Seq2[filesystem.FileEntry, error]) {
+	return func(yield func(filesystem.FileEntry, error) bool) {
+		for entry, err := range b.Filesystem.Files(ctx, options...) {
+			if err == nil && yield(entry, nil) {
+				yield(nil, errors.New("connection reset"))
+			}
+			return
+		}
+	}
+}
+
+func Test_Sync_IncompleteListing(t *testing.T) {
+	assertions := assert.New(t)
+
+	src := &brokenListing{randomfs.New(testsuite.GenerateLocations(10), 1024)}
+
+	dstTmpDir, err := os.MkdirTemp("", "*")
+	if !assertions.Nil(err, "failed create temporary directory") {
+		return
+	}
+	defer os.RemoveAll(dstTmpDir)
+	dst := directory.New(dstTmpDir, 0o777, 0o777)
+
+	ctx, cancel := context.WithTimeout(context.TODO(), time.Minute)
+	defer cancel()
+
+	_, err = filesystem.Sync(ctx, dst, src)
+	assertions.ErrorIs(err, filesystem.ErrIncompleteListing, "sync should fail on incomplete listings")
+
+	_, err = filesystem.SyncWorkers(2, ctx, dst, src)
+	assertions.ErrorIs(err, filesystem.ErrIncompleteListing, "sync should fail on incomplete listings")
+}
+
+// Fails every open
+type brokenOpen struct {
+	filesystem.Filesystem
+}
+
+func (b *brokenOpen) Open(ctx context.Context, location []string) (rc io.ReadCloser, err error) {
+	return nil, errors.New("connection reset")
+}
+
+// Fails the first attempts of every file with a transient error
+type flakyOpen struct {
+	filesystem.Filesystem
+	failures int
+
+	mutex    sync.Mutex
+	attempts map[string]int
+}
+
+func (f *flakyOpen) Open(ctx context.Context, location []string) (rc io.ReadCloser, err error) {
+	f.mutex.Lock()
+	f.attempts[path.Join(location...)]++
+	attempts := f.attempts[path.Join(location...)]
+	f.mutex.Unlock()
+
+	if attempts <= f.failures {
+		return nil, fmt.Errorf("failed to open: %w", filesystem.ErrTransient)
+	}
+	return f.Filesystem.Open(ctx, location)
+}
+
+func Test_Sync_Retry(t *testing.T) {
+	if os.Getuid() == 0 {
+		t.Skip("Can't run this test as root")
+		return
+	}
+
+	const files = 10
+	newSrc := func(failures int) (src *flakyOpen) {
+		return &flakyOpen{
+			Filesystem: randomfs.New(testsuite.GenerateLocations(files), 1024),
+			failures:   failures,
+			attempts:   map[string]int{},
+		}
+	}
+
+	t.Run("Recovered", func(t *testing.T) {
+		assertions := assert.New(t)
+
+		ctx, cancel := context.WithTimeout(context.TODO(), time.Minute)
+		defer cancel()
+
+		src := newSrc(2)
+		dst := directory.New(t.TempDir(), 0o777, 0o777)
+
+		result, err := filesystem.Sync(ctx, dst, src,
+			filesystem.WithSyncOptionWorkers(4),
+			filesystem.WithSyncOptionRetryBackoff(time.Millisecond, 10*time.Millisecond),
+		)
+		if !assertions.Nil(err, "failed to sync files") {
+			return
+		}
+		assertions.Zero(result.Failed, "transient failures should be retried")
+		assertions.Equal(int64(len(src.attempts)), result.Copied, "every file should be copied")
+		assertions.Equal(2*result.Copied, result.Retried, "every file should be retried twice")
+	})
+	t.Run("Exhausted", func(t *testing.T) {
+		assertions := assert.New(t)
+
+		ctx, cancel := context.WithTimeout(context.TODO(), time.Minute)
+		defer cancel()
+
+		src := newSrc(10)
+		dst := directory.New(t.TempDir(), 0o777, 0o777)
+
+		result, err := filesystem.Sync(ctx, dst, src,
+			filesystem.WithSyncOptionWorkers(4),
+			filesystem.WithSyncOptionRetries(2),
+			filesystem.WithSyncOptionRetryBackoff(time.Millisecond, 10*time.Millisecond),
+		)
+		if !assertions.Nil(err, "failed files should not stop the sync") {
+			return
+		}
+		assertions.Equal(int64(len(src.attempts)), result.Failed, "every file should fail")
+		assertions.Equal(2*result.Failed, result.Retried, "every file should be retried twice")
+		for _, attempts := range src.attempts {
+			assertions.Equal(3, attempts, "every file should be attempted three times")
+		}
+	})
+	t.Run("Disabled", func(t *testing.T) {
+		assertions := assert.New(t)
+
+		ctx, cancel := context.WithTimeout(context.TODO(), time.Minute)
+		defer cancel()
+
+		src := newSrc(1)
+		dst := directory.New(t.TempDir(), 0o777, 0o777)
+
+		result, err := filesystem.Sync(ctx, dst, src, filesystem.WithSyncOptionRetries(0))
+		if !assertions.Nil(err, "failed files should not stop the sync") {
+			return
+		}
+		assertions.Equal(int64(len(src.attempts)), result.Failed, "every file should fail")
+		assertions.Zero(result.Retried, "no file should be retried")
+	})
+	t.Run("Canceled", func(t *testing.T) {
+		assertions := assert.New(t)
+
+		ctx, cancel := context.WithTimeout(context.TODO(), 100*time.Millisecond)
+		defer cancel()
+
+		src := newSrc(1)
+		dst := directory.New(t.TempDir(), 0o777, 0o777)
+
+		start := time.Now()
+		_, err := filesystem.Sync(ctx, dst, src, filesystem.WithSyncOptionRetryBackoff(time.Hour, time.Hour))
+		assertions.ErrorIs(err, context.DeadlineExceeded, "context errors should stop the retries")
+		assertions.Less(time.Since(start), 10*time.Second, "backoff should be interrupted")
+	})
+}
+
+// Opens fail as if the listed files were removed, writes fail as if the dst bucket was missing
+type missingFiles struct {
+	filesystem.Filesystem
+	missingSrc bool
+}
+
+func (m *missingFiles) Open(ctx context.Context, location []string) (rc io.ReadCloser, err error) {
+	if m.missingSrc {
+		return nil, fmt.Errorf("failed to open: %w", fs.ErrNotExist)
+	}
+	return m.Filesystem.Open(ctx, location)
+}
+
+func (m *missingFiles) WriteFile(ctx context.Context, location []string, src io.Reader, modTime time.Time) (finalLocation []string, err error) {
+	if !m.missingSrc {
+		return nil, fmt.Errorf("failed to write: %w", fs.ErrNotExist)
+	}
+	return m.Filesystem.WriteFile(ctx, location, src, modTime)
+}
+
+func Test_Sync_NotExist(t *testing.T) {
+	if os.Getuid() == 0 {
+		t.Skip("Can't run this test as root")
+		return
+	}
+
+	const files = 10
+
+	t.Run("Src", func(t *testing.T) {
+		assertions := assert.New(t)
+
+		ctx, cancel := context.WithTimeout(context.TODO(), time.Minute)
+		defer cancel()
+
+		src := &missingFiles{Filesystem: randomfs.New(testsuite.GenerateLocations(files), 1024), missingSrc: true}
+		dst := directory.New(t.TempDir(), 0o777, 0o777)
+
+		result, err := filesystem.Sync(ctx, dst, src)
+		if !assertions.Nil(err, "failed to sync files") {
+			return
+		}
+		assertions.Equal(int64(files), result.Skipped, "files removed from src should be skipped")
+		assertions.Zero(result.Failed, "files removed from src should not fail")
+	})
+	t.Run("Dst", func(t *testing.T) {
+		assertions := assert.New(t)
+
+		ctx, cancel := context.WithTimeout(context.TODO(), time.Minute)
+		defer cancel()
+
+		src := randomfs.New(testsuite.GenerateLocations(files), 1024)
+		dst := &missingFiles{Filesystem: directory.New(t.TempDir(), 0o777, 0o777)}
+
+		result, err := filesystem.Sync(ctx, dst, src)
+		if !assertions.Nil(err, "failed to sync files") {
+			return
+		}
+		assertions.Equal(int64(files), result.Failed, "missing dst should fail every file")
+		assertions.Len(result.Errors, files, "failures should be reported")
+		assertions.Zero(result.Skipped, "missing dst should not skip files")
+	})
+}
+
+// Streams every file through Create, buffered writes fail
+type streamingOnly struct {
+	*directory.Directory
+}
+
+func (s *streamingOnly) WriteFile(ctx context.Context, location []string, src io.Reader, modTime time.Time) (finalLocation []string, err error) {
+	return nil, errors.New("contents should be streamed through Create")
+}
+
+func Test_Sync_Create(t *testing.T) {
+	if os.Getuid() == 0 {
+		t.Skip("Can't run this test as root")
+		return
+	}
+
+	assertions := assert.New(t)
+
+	ctx, cancel := context.WithTimeout(context.TODO(), time.Minute)
+	defer cancel()
+
+	const files = 10
+	src := randomfs.New(testsuite.GenerateLocations(files), 1024)
+	dst := &streamingOnly{Directory: directory.New(t.TempDir(), 0o777, 0o777)}
+
+	result, err := filesystem.Sync(ctx, dst, src)
+	if !assertions.Nil(err, "failed to sync files") {
+		return
+	}
+	assertions.Equal(int64(files), result.Copied, "files should be copied through Create")
+	assertions.Zero(result.Failed, "no file should fail")
+}
+
+func Test_Sync_Options(t *testing.T) {
+	if os.Getuid() == 0 {
+		t.Skip("Can't run this test as root")
+		return
+	}
+
+	newDst := func(t *testing.T) (dst filesystem.Filesystem) {
+		dstTmpDir := t.TempDir()
+		return directory.New(dstTmpDir, 0o777, 0o777)
+	}
+	countFiles := func(ctx context.Context, fs filesystem.Filesystem) (count int) {
+		for _, err := range fs.Files(ctx) {
+			if err == nil {
+				count++
+			}
+		}
+		return count
+	}
+
+	t.Run("FailFast", func(t *testing.T) {
+		assertions := assert.New(t)
+
+		ctx, cancel := context.WithTimeout(context.TODO(), time.Minute)
+		defer cancel()
+
+		src := &brokenOpen{randomfs.New(testsuite.GenerateLocations(10), 1024)}
+
+		result, err := filesystem.Sync(ctx, newDst(t), src, filesystem.WithSyncOptionWorkers(4))
+		assertions.Nil(err, "failed files should not stop the sync")
+		assertions.Equal(int64(10), result.Failed, "every file should fail")
+		assertions.Len(result.Errors, 10, "every failure should be recorded")
+		assertions.NotNil(result.Err(), "failures should be joined")
+
+		_, err = filesystem.Sync(ctx, newDst(t), src, filesystem.WithSyncOptionWorkers(4), filesystem.WithSyncOptionFailFast(true))
+		assertions.NotNil(err, "failed files should stop the sync")
+
+		err = filesystem.Copy(ctx, newDst(t), src)
+		assertions.NotNil(err, "failed files should stop the copy")
+	})
+	t.Run("MaxFiles", func(t *testing.T) {
+		assertions := assert.New(t)
+
+		ctx, cancel := context.WithTimeout(context.TODO(), time.Minute)
+		defer cancel()
+
+		src := randomfs.New(testsuite.GenerateLocations(10), 1024)
+		dst := newDst(t)
+
+		_, err := filesystem.Sync(ctx, dst, src, filesystem.WithSyncOptionWorkers(4), filesystem.WithSyncOptionMaxFiles(3))
+		if !assertions.Nil(err, "failed to sync files") {
+			return
+		}
+		assertions.Equal(3, countFiles(ctx, dst), "only max files should be synchronized")
+	})
+	t.Run("ModTime", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.TODO(), time.Minute)
+		defer cancel()
+
+		src := randomfs.New(testsuite.GenerateLocations(5), 1024)
+
+		t.Run("Preserve", func(t *testing.T) {
+			assertions := assert.New(t)
+
+			dst := newDst(t)
+			_, err := filesystem.Sync(ctx, dst, src)
+			if !assertions.Nil(err, "failed to sync files") {
+				return
+			}
+
+			for entry, err := range src.Files(ctx) {
+				if !assertions.Nil(err, "failed to list files") {
+					return
+				}
+				checksum, err := dst.ChecksumTime(ctx, entry.Location())
+				if !assertions.Nil(err, "failed to get checksum") {
+					return
+				}
+				assertions.Equal(ioutils.ChecksumTime(entry.ModTime()), checksum, "mod time should be preserved")
+			}
+		})
+		t.Run("SyncStart", func(t *testing.T) {
+			assertions := assert.New(t)
+
+			start := time.Now().Truncate(time.Second)
+
+			dst := newDst(t)
+			_, err := filesystem.Sync(ctx, dst, src, filesystem.WithSyncOptionModTime(filesystem.ModTimeSyncStart))
+			if !assertions.Nil(err, "failed to sync files") {
+				return
+			}
+
+			for entry, err := range dst.Files(ctx) {
+				if !assertions.Nil(err, "failed to list files") {
+					return
+				}
+				assertions.False(entry.ModTime().Before(start), "mod time should be the sync start")
+			}
+		})
+	})
+}
+
+func Test_Sync_Observer(t *testing.T) {
+	if os.Getuid() == 0 {
+		t.Skip("Can't run this test as root")
+		return
+	}
+
+	assertions := assert.New(t)
+
+	ctx, cancel := context.WithTimeout(context.TODO(), time.Minute)
+	defer cancel()
+
+	const (
+		files    = 10
+		fileSize = 128 * 1024
+	)
+	src := directory.New(t.TempDir(), 0o777, 0o777)
+	_, err := filesystem.Sync(ctx, src, randomfs.New(testsuite.GenerateLocations(files), fileSize))
+	if !assertions.Nil(err, "failed to prepare src files") {
+		return
+	}
+	dst := directory.New(t.TempDir(), 0o777, 0o777)
+
+	var (
+		counts   = map[filesystem.EventKind]int{}
+		progress = map[string]int64{}
+		finished *filesystem.SyncResult
+	)
+	observer := func(event filesystem.Event) {
+		counts[event.Kind]++
+		switch event.Kind {
+		case filesystem.EventProgress:
+			key := path.Join(event.Location...)
+			assertions.Greater(event.Bytes, progress[key], "progress should grow")
+			progress[key] = event.Bytes
+		case filesystem.EventCompleted:
+			assertions.Equal(int64(fileSize), event.Bytes, "completed files should report their size")
+		case filesystem.EventFinished:
+			finished = event.Result
+		}
+	}
+
+	result, err := filesystem.Sync(ctx, dst, src, filesystem.WithSyncOptionWorkers(4), filesystem.WithSyncOptionObserver(observer))
+	if !assertions.Nil(err, "failed to sync files") {
+		return
+	}
+	assertions.Equal(files, counts[filesystem.EventListed], "every file should be listed")
+	assertions.Equal(files, counts[filesystem.EventStarted], "every file should be started")
+	assertions.Equal(files, counts[filesystem.EventCompleted], "every file should be completed")
+	assertions.Equal(1, counts[filesystem.EventFinished], "the sync should finish once")
+	assertions.Len(progress, files, "every file should report progress")
+	for _, bytes := range progress {
+		assertions.Equal(int64(fileSize), bytes, "progress should reach the file size")
+	}
+	assertions.Same(result, finished, "finished event should hold the result")
+
+	clear(counts)
+	clear(progress)
+	_, err = filesystem.Sync(ctx, dst, src, filesystem.WithSyncOptionObserver(observer))
+	if !assertions.Nil(err, "failed to sync files") {
+		return
+	}
+	assertions.Equal(files, counts[filesystem.EventSkipped], "unchanged files should be skipped")
+	assertions.Zero(counts[filesystem.EventStarted], "unchanged files should not be started")
+}
+
+// Counts the metadata requests
+type countingStat struct {
+	filesystem.Filesystem
+	stats atomic.Int64
+}
+
+func (c *countingStat) Stat(ctx context.Context, location []string) (info *filesystem.FileInfo, err error) {
+	c.stats.Add(1)
+	return c.Filesystem.Stat(ctx, location)
+}
+
+func Test_Sync_State(t *testing.T) {
+	if os.Getuid() == 0 {
+		t.Skip("Can't run this test as root")
+		return
+	}
+
+	assertions := assert.New(t)
+
+	ctx, cancel := context.WithTimeout(context.TODO(), time.Minute)
+	defer cancel()
+
+	const files = 10
+	src := directory.New(t.TempDir(), 0o777, 0o777)
+	_, err := filesystem.Sync(ctx, src, randomfs.New(testsuite.GenerateLocations(files), 1024))
+	if !assertions.Nil(err, "failed to prepare src files") {
+		return
+	}
+	dst := &countingStat{Filesystem: directory.New(t.TempDir(), 0o777, 0o777)}
+
+	state, err := journal.Open(path.Join(t.TempDir(), "journal"))
+	if !assertions.Nil(err, "failed to open journal") {
+		return
+	}
+	defer state.Close()
+
+	result, err := filesystem.Sync(ctx, dst, src, filesystem.WithSyncOptionState(state))
+	if !assertions.Nil(err, "failed to sync files") {
+		return
+	}
+	assertions.Equal(result.Copied, int64(state.Len()), "every copied file should be recorded")
+
+	t.Run("Second Time", func(t *testing.T) {
+		assertions := assert.New(t)
+
+		dst.stats.Store(0)
+		result, err := filesystem.Sync(ctx, dst, src, filesystem.WithSyncOptionState(state))
+		if !assertions.Nil(err, "failed to sync files") {
+			return
+		}
+		assertions.Equal(int64(state.Len()), result.Skipped, "recorded files should be skipped")
+		assertions.Zero(dst.stats.Load(), "recorded files should not be compared with dst")
+	})
+	t.Run("Mirror", func(t *testing.T) {
+		assertions := assert.New(t)
+
+		plan, err := filesystem.Plan(ctx, dst, src, filesystem.WithSyncOptionState(state), filesystem.WithSyncOptionMirror(true))
+		if !assertions.Nil(err, "failed to plan sync") {
+			return
+		}
+		for _, action := range plan.Actions {
+			assertions.Equal(filesystem.ActionSkip, action.Kind, "recorded files should be skipped")
+			assertions.Equal("unchanged since the last sync", action.Reason, "skip should come from the state")
+		}
+	})
+}
+
+func Test_Sync_RateLimit(t *testing.T) {
+	if os.Getuid() == 0 {
+		t.Skip("Can't run this test as root")
+		return
+	}
+
+	const (
+		files    = 4
+		fileSize = 64 * 1024
+		rate     = 128 * 1024
+	)
+	src := randomfs.New(testsuite.GenerateLocations(files), fileSize)
+
+	t.Run("Limited", func(t *testing.T) {
+		assertions := assert.New(t)
+
+		ctx, cancel := context.WithTimeout(context.TODO(), time.Minute)
+		defer cancel()
+
+		limiter := ioutils.NewRateLimiter(ioutils.ConstantRate(rate))
+		result, err := filesystem.Sync(ctx, directory.New(t.TempDir(), 0o777, 0o777), src,
+			filesystem.WithSyncOptionWorkers(files),
+			filesystem.WithSyncOptionRateLimiter(limiter),
+		)
+		if !assertions.Nil(err, "failed to sync files") {
+			return
+		}
+
+		// The first second of transfer is covered by the initial bucket
+		expected := time.Duration(float64(result.Bytes-rate) / rate * float64(time.Second))
+		assertions.GreaterOrEqual(result.Duration, expected*8/10, "transfers should share the rate")
+	})
+	t.Run("Scheduled", func(t *testing.T) {
+		assertions := assert.New(t)
+
+		ctx, cancel := context.WithTimeout(context.TODO(), time.Minute)
+		defer cancel()
+
+		// Unlimited during the whole day, overriding the tiny fallback rate
+		limiter := ioutils.NewRateLimiter(ioutils.ScheduledRate(1, []ioutils.RateWindow{{From: 0, To: 24 * time.Hour}}))
+		result, err := filesystem.Sync(ctx, directory.New(t.TempDir(), 0o777, 0o777), src,
+			filesystem.WithSyncOptionWorkers(files),
+			filesystem.WithSyncOptionRateLimiter(limiter),
+		)
+		if !assertions.Nil(err, "failed to sync files") {
+			return
+		}
+		assertions.Less(result.Duration, 10*time.Second, "the window should lift the limit")
+	})
+}
+
+func Test_Sync_Mirror(t *testing.T) {
+	if os.Getuid() == 0 {
+		t.Skip("Can't run this test as root")
+		return
+	}
+
+	// Prepares a dst holding the src files plus the extra ones
+	prepare := func(t *testing.T, src filesystem.Filesystem, extra int) (dst filesystem.Filesystem, extraFiles [][]string, ok bool) {
+		assertions := assert.New(t)
+
+		ctx, cancel := context.WithTimeout(context.TODO(), time.Minute)
+		defer cancel()
+
+		dst = directory.New(t.TempDir(), 0o777, 0o777)
+
+		extraFiles = testsuite.GenerateLocations(extra)
+		err := filesystem.Copy(ctx, dst, randomfs.New(extraFiles, 1024))
+		if !assertions.Nil(err, "failed to copy extra files") {
+			return nil, nil, false
+		}
+
+		err = filesystem.Copy(ctx, dst, src)
+		if !assertions.Nil(err, "failed to copy src files") {
+			return nil, nil, false
+		}
+		return dst, extraFiles, true
+	}
+
+	t.Run("Succeed", func(t *testing.T) {
+		assertions := assert.New(t)
+
+		ctx, cancel := context.WithTimeout(context.TODO(), time.Minute)
+		defer cancel()
+
+		srcFiles := testsuite.GenerateLocations(10)
+		src := randomfs.New(srcFiles, 1024)
+
+		dst, extraFiles, ok := prepare(t, src, 2)
+		if !ok {
+			return
+		}
+
+		_, err := filesystem.Sync(ctx, dst, src, filesystem.WithSyncOptionMirror(true))
+		if !assertions.Nil(err, "failed to mirror files") {
+			return
+		}
+
+		for _, location := range extraFiles {
+			_, err = dst.Stat(ctx, location)
+			assertions.ErrorIs(err, fs.ErrNotExist, "files missing from src should be removed")
+		}
+		for _, location := range srcFiles {
+			_, err = dst.Stat(ctx, location)
+			assertions.Nil(err, "src files should be kept")
+		}
+	})
+	t.Run("TooManyDeletions", func(t *testing.T) {
+		assertions := assert.New(t)
+
+		ctx, cancel := context.WithTimeout(context.TODO(), time.Minute)
+		defer cancel()
+
+		src := randomfs.New(testsuite.GenerateLocations(2), 1024)
+
+		dst, extraFiles, ok := prepare(t, src, 10)
+		if !ok {
+			return
+		}
+
+		_, err := filesystem.Sync(ctx, dst, src, filesystem.WithSyncOptionMirror(true), filesystem.WithSyncOptionMaxDeletePercent(25))
+		assertions.ErrorIs(err, filesystem.ErrTooManyDeletions, "mirror should refuse removing most files")
+
+		for _, location := range extraFiles {
+			_, err = dst.Stat(ctx, location)
+			assertions.Nil(err, "refused mirror should not remove files")
+		}
+	})
+	t.Run("IncompleteListing", func(t *testing.T) {
+		assertions := assert.New(t)
+
+		ctx, cancel := context.WithTimeout(context.TODO(), time.Minute)
+		defer cancel()
+
+		src := randomfs.New(testsuite.GenerateLocations(10), 1024)
+
+		dst, extraFiles, ok := prepare(t, src, 1)
+		if !ok {
+			return
+		}
+
+		_, err := filesystem.Sync(ctx, dst, &brokenListing{src}, filesystem.WithSyncOptionMirror(true))
+		assertions.ErrorIs(err, filesystem.ErrIncompleteListing, "mirror should refuse incomplete listings")
+
+		_, err = filesystem.Sync(ctx, dst, src, filesystem.WithSyncOptionMirror(true), filesystem.WithSyncOptionMaxFiles(5))
+		assertions.ErrorIs(err, filesystem.ErrIncompleteListing, "mirror should refuse limited listings")
+
+		for _, location := range extraFiles {
+			_, err = dst.Stat(ctx, location)
+			assertions.Nil(err, "refused mirror should not remove files")
+		}
+	})
+}
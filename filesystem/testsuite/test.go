@@ -20,9 +20,12 @@ import (
 	"context"
 	"crypto/sha256"
 	"encoding/hex"
+	"fmt"
 	"io"
+	"io/fs"
 	"iter"
 	"os"
+	"slices"
 	"testing"
 	"time"
 
@@ -64,7 +67,10 @@ func TestFilesystem(t *testing.T, baseFs filesystem.Filesystem) func(t *testing.
 				defer cancel()
 
 				var count int
-				for range testFs.Files(ctx) {
+				for _, err := range testFs.Files(ctx) {
+					if !assertions.Nil(err, "failed to list files") {
+						return
+					}
 					count++
 				}
 				assertions.NotZero(count, "should found the expected number of files")
@@ -76,16 +82,16 @@ func TestFilesystem(t *testing.T, baseFs filesystem.Filesystem) func(t *testing.
 					ctx, cancel := context.WithTimeout(context.TODO(), time.Microsecond)
 					defer cancel()
 
-					pull, stop := iter.Pull(testFs.Files(ctx))
+					pull, stop := iter.Pull2(testFs.Files(ctx))
 					for range 10 {
-						_, valid := pull()
+						_, _, valid := pull()
 						if !valid {
 							break
 						}
 					}
 					stop()
 
-					_, valid := pull()
+					_, _, valid := pull()
 					assertions.False(valid, "should be invalid after stop()")
 				})
 				t.Run("Timeout", func(t *testing.T) {
@@ -95,13 +101,181 @@ func TestFilesystem(t *testing.T, baseFs filesystem.Filesystem) func(t *testing.
 					defer cancel()
 
 					var timeoutCount int
-					for range testFs.Files(ctx) {
+					var timeoutErr error
+					for _, err := range testFs.Files(ctx) {
+						if err != nil {
+							timeoutErr = err
+							break
+						}
 						timeoutCount++
 					}
 					assertions.Less(timeoutCount, count, "should not find all files due to timeout")
+					assertions.NotNil(timeoutErr, "should report the incomplete listing")
+				})
+				t.Run("Metadata", func(t *testing.T) {
+					assertions := assert.New(t)
+
+					ctx, cancel := context.WithTimeout(context.TODO(), time.Minute)
+					defer cancel()
+
+					var checked int
+					for entry, err := range testFs.Files(ctx) {
+						if !assertions.Nil(err, "failed to list files") {
+							return
+						}
+
+						info, err := testFs.Stat(ctx, entry.Location())
+						if !assertions.Nil(err, "failed to stat listed file") {
+							return
+						}
+
+						if !assertions.Equal(ioutils.ChecksumTime(info.ModTime), ioutils.ChecksumTime(entry.ModTime()), "listed mod time doesn't match") {
+							return
+						}
+
+						if entry.Size() >= 0 && info.Size >= 0 {
+							if !assertions.Equal(info.Size, entry.Size(), "listed size doesn't match") {
+								return
+							}
+						}
+
+						checked++
+						if checked >= 10 {
+							break
+						}
+					}
+				})
+				t.Run("Prefix", func(t *testing.T) {
+					assertions := assert.New(t)
+
+					ctx, cancel := context.WithTimeout(context.TODO(), time.Minute)
+					defer cancel()
+
+					// Use the parent directory of any nested file as prefix
+					var prefix []string
+					for entry, err := range testFs.Files(ctx) {
+						if !assertions.Nil(err, "failed to list files") {
+							return
+						}
+
+						location := entry.Location()
+						if len(location) > 1 {
+							prefix = slices.Clone(location[:len(location)-1])
+							break
+						}
+					}
+					if !assertions.NotEmpty(prefix, "should find at least one nested file") {
+						return
+					}
+					t.Logf("Prefix: %v", prefix)
+
+					var recursiveCount int
+					for entry, err := range testFs.Files(ctx, filesystem.WithListOptionPrefix(prefix...)) {
+						if !assertions.Nil(err, "failed to list files") {
+							return
+						}
+
+						location := entry.Location()
+						if !assertions.Greater(len(location), len(prefix), "location should be deeper than the prefix") {
+							return
+						}
+						if !assertions.Equal(prefix, location[:len(prefix)], "location should start with the prefix") {
+							return
+						}
+						recursiveCount++
+					}
+					assertions.NotZero(recursiveCount, "should find files under the prefix")
+
+					var directCount int
+					for entry, err := range testFs.Files(ctx, filesystem.WithListOptionPrefix(prefix...), filesystem.WithListOptionRecursive(false)) {
+						if !assertions.Nil(err, "failed to list files") {
+							return
+						}
+
+						location := entry.Location()
+						if !assertions.Len(location, len(prefix)+1, "location should be directly inside the prefix") {
+							return
+						}
+						if !assertions.Equal(prefix, location[:len(prefix)], "location should start with the prefix") {
+							return
+						}
+						directCount++
+					}
+					assertions.NotZero(directCount, "should find files directly inside the prefix")
+					assertions.LessOrEqual(directCount, recursiveCount, "non recursive listing can't return more files than the recursive one")
 				})
 			})
 
+			t.Run("NotExist", func(t *testing.T) {
+				assertions := assert.New(t)
+
+				ctx, cancel := context.WithTimeout(context.TODO(), time.Minute)
+				defer cancel()
+
+				missingLocation := GenerateFilename(5)
+
+				_, err := testFs.Stat(ctx, missingLocation)
+				assertions.ErrorIs(err, fs.ErrNotExist, "stat of missing file should report fs.ErrNotExist")
+
+				rc, err := testFs.Open(ctx, missingLocation)
+				if err == nil {
+					rc.Close()
+				}
+				assertions.ErrorIs(err, fs.ErrNotExist, "open of missing file should report fs.ErrNotExist")
+			})
+
+			t.Run("InvalidLocation", func(t *testing.T) {
+				ctx, cancel := context.WithTimeout(context.TODO(), time.Minute)
+				defer cancel()
+
+				writable := filesystem.RequireCapabilities(testFs, filesystem.CapabilityWrite) == nil
+				removable := filesystem.RequireCapabilities(testFs, filesystem.CapabilityRemove) == nil
+
+				invalidLocations := [][]string{
+					// Empty locations would refer to the whole filesystem
+					nil,
+					{"..", "..", "escape.txt"},
+					{"dir", "..", "..", "escape.txt"},
+					{"/tmp", "escape.txt"},
+					{"dir", "", "escape.txt"},
+					{"escape\x00.txt"},
+				}
+				for _, location := range invalidLocations {
+					t.Run(fmt.Sprintf("%q", location), func(t *testing.T) {
+						assertions := assert.New(t)
+
+						_, err := testFs.Stat(ctx, location)
+						assertions.ErrorIs(err, fs.ErrInvalid, "stat of invalid location should report fs.ErrInvalid")
+
+						rc, err := testFs.Open(ctx, location)
+						if err == nil {
+							rc.Close()
+						}
+						assertions.ErrorIs(err, fs.ErrInvalid, "open of invalid location should report fs.ErrInvalid")
+
+						if !writable {
+							return
+						}
+
+						_, err = testFs.WriteFile(ctx, location, bytes.NewReader(samplesfiles.Lorem), time.Now())
+						assertions.ErrorIs(err, fs.ErrInvalid, "write of invalid location should report fs.ErrInvalid")
+
+						_, err = testFs.Move(ctx, GenerateFilename(5), location)
+						assertions.ErrorIs(err, fs.ErrInvalid, "move into invalid location should report fs.ErrInvalid")
+
+						_, err = testFs.Move(ctx, location, GenerateFilename(5))
+						assertions.ErrorIs(err, fs.ErrInvalid, "move from invalid location should report fs.ErrInvalid")
+
+						if !removable {
+							return
+						}
+
+						err = testFs.RemoveAll(ctx, location)
+						assertions.ErrorIs(err, fs.ErrInvalid, "removal of invalid location should report fs.ErrInvalid")
+					})
+				}
+			})
+
 			t.Run("Write", func(t *testing.T) {
 				assertions := assert.New(t)
 
@@ -162,6 +336,23 @@ func TestFilesystem(t *testing.T, baseFs filesystem.Filesystem) func(t *testing.
 					})
 				})
 
+				t.Run("Stat", func(t *testing.T) {
+					assertions := assert.New(t)
+
+					info, err := testFs.Stat(ctx, targetLocation)
+					if !assertions.Nil(err, "failed to stat file") {
+						return
+					}
+					t.Logf("FS Info: %+v", info)
+
+					if !assertions.Equal(ioutils.ChecksumTime(modTime), ioutils.ChecksumTime(info.ModTime), "mod time doesn't match with the one written") {
+						return
+					}
+
+					if info.Size >= 0 {
+						assertions.Equal(counter.Count(), info.Size, "size doesn't match with the one written")
+					}
+				})
 				t.Run("Open checksum", func(t *testing.T) {
 					assertions := assert.New(t)
 
@@ -183,6 +374,42 @@ func TestFilesystem(t *testing.T, baseFs filesystem.Filesystem) func(t *testing.
 
 					assertions.Equal(referenceChecksum, openChecksum, "reference checksum must match open checksum")
 				})
+				t.Run("OpenRange", func(t *testing.T) {
+					size := int64(len(samplesfiles.Lorem))
+
+					tests := []struct {
+						Name     string
+						Offset   int64
+						Length   int64
+						Expected []byte
+					}{
+						{Name: "Head", Offset: 0, Length: 512, Expected: samplesfiles.Lorem[:512]},
+						{Name: "Middle", Offset: 100, Length: 200, Expected: samplesfiles.Lorem[100:300]},
+						{Name: "Tail", Offset: size - 10, Length: -1, Expected: samplesfiles.Lorem[size-10:]},
+						{Name: "Exceeding", Offset: size - 10, Length: 100, Expected: samplesfiles.Lorem[size-10:]},
+						{Name: "AfterEnd", Offset: size + 10, Length: 100, Expected: []byte{}},
+					}
+					for _, test := range tests {
+						t.Run(test.Name, func(t *testing.T) {
+							assertions := assert.New(t)
+
+							ctx, cancel := context.WithTimeout(context.TODO(), time.Minute)
+							defer cancel()
+
+							rc, err := filesystem.OpenRange(ctx, testFs, targetLocation, test.Offset, test.Length)
+							if !assertions.Nil(err, "failed to open range") {
+								return
+							}
+							defer rc.Close()
+
+							contents, err := io.ReadAll(rc)
+							if !assertions.Nil(err, "failed to read range") {
+								return
+							}
+							assertions.Equal(test.Expected, contents, "range contents doesn't match")
+						})
+					}
+				})
 				t.Run("Moving File", func(t *testing.T) {
 					assertions := assert.New(t)
 
@@ -237,6 +464,146 @@ func TestFilesystem(t *testing.T, baseFs filesystem.Filesystem) func(t *testing.
 					}
 				})
 			})
+			t.Run("RemoveAll", func(t *testing.T) {
+				if filesystem.RequireCapabilities(testFs, filesystem.CapabilityWrite|filesystem.CapabilityRemove) != nil {
+					t.Skip("Filesystem is not writable")
+					return
+				}
+
+				assertions := assert.New(t)
+
+				ctx, cancel := context.WithTimeout(context.TODO(), time.Minute)
+				defer cancel()
+
+				base := GenerateFilename(2)
+				nested := [][]string{
+					slices.Concat(base, []string{"first.txt"}),
+					slices.Concat(base, []string{"sub", "second.txt"}),
+					slices.Concat(base, []string{"sub", "deep", "third.txt"}),
+				}
+				// Shares the prefix as a string but not as a location
+				sibling := []string{base[0], base[1] + "-sibling", "kept.txt"}
+
+				var written [][]string
+				for _, location := range append(nested, sibling) {
+					finalLocation, err := testFs.WriteFile(ctx, location, bytes.NewReader(samplesfiles.Lorem), time.Now())
+					if !assertions.Nil(err, "failed to write file") {
+						return
+					}
+					written = append(written, finalLocation)
+				}
+				defer testFs.RemoveAll(ctx, written[len(written)-1])
+
+				// Filesystems may relocate the written files
+				removed := written[0][:len(written[0])-1]
+
+				err := testFs.RemoveAll(ctx, removed)
+				if !assertions.Nil(err, "failed to remove prefix") {
+					return
+				}
+
+				for _, location := range written[:len(nested)] {
+					_, err = testFs.Stat(ctx, location)
+					assertions.ErrorIs(err, fs.ErrNotExist, "files under the removed prefix should not exist")
+				}
+
+				_, err = testFs.Stat(ctx, written[len(written)-1])
+				assertions.Nil(err, "files outside the removed prefix should be kept")
+
+				for entry, err := range testFs.Files(ctx, filesystem.WithListOptionPrefix(removed...)) {
+					if !assertions.Nil(err, "failed to list files") {
+						return
+					}
+					assertions.Fail("removed prefix should not list files", "found: %v", entry.Location())
+				}
+
+				err = testFs.RemoveAll(ctx, GenerateFilename(5))
+				assertions.Nil(err, "removing a missing location should succeed")
+			})
+
+			t.Run("Create", func(t *testing.T) {
+				if filesystem.RequireCapabilities(testFs, filesystem.CapabilityWrite) != nil {
+					t.Skip("Filesystem is not writable")
+					return
+				}
+
+				t.Run("Commit", func(t *testing.T) {
+					assertions := assert.New(t)
+
+					ctx, cancel := context.WithTimeout(context.TODO(), time.Minute)
+					defer cancel()
+
+					modTime := time.Now()
+					location := GenerateFilename(5)
+
+					w, err := filesystem.Create(ctx, testFs, location, modTime)
+					if !assertions.Nil(err, "failed to create file") {
+						return
+					}
+					defer func() { testFs.RemoveAll(ctx, w.Location()) }()
+
+					// Write by small chunks as a streaming producer would do
+					for chunk := range slices.Chunk(samplesfiles.Lorem, 1024) {
+						_, err = w.Write(chunk)
+						if !assertions.Nil(err, "failed to write chunk") {
+							w.Abort()
+							return
+						}
+					}
+
+					err = w.Close()
+					if !assertions.Nil(err, "failed to commit file") {
+						return
+					}
+					location = w.Location()
+
+					rc, err := testFs.Open(ctx, location)
+					if !assertions.Nil(err, "failed to open file") {
+						return
+					}
+					defer rc.Close()
+
+					contents, err := io.ReadAll(rc)
+					if !assertions.Nil(err, "failed to read file") {
+						return
+					}
+					assertions.Equal(samplesfiles.Lorem, contents, "contents doesn't match the written ones")
+
+					checksum, err := testFs.ChecksumTime(ctx, location)
+					if !assertions.Nil(err, "failed to compute file checksum") {
+						return
+					}
+					assertions.Equal(ioutils.ChecksumTime(modTime), checksum, "mod time doesn't match with the one written")
+				})
+				t.Run("Abort", func(t *testing.T) {
+					assertions := assert.New(t)
+
+					ctx, cancel := context.WithTimeout(context.TODO(), time.Minute)
+					defer cancel()
+
+					location := GenerateFilename(5)
+
+					w, err := filesystem.Create(ctx, testFs, location, time.Now())
+					if !assertions.Nil(err, "failed to create file") {
+						return
+					}
+
+					_, err = w.Write(samplesfiles.Lorem)
+					if !assertions.Nil(err, "failed to write contents") {
+						w.Abort()
+						return
+					}
+
+					err = w.Abort()
+					if !assertions.Nil(err, "failed to abort file") {
+						return
+					}
+					assertions.Nil(w.Close(), "close after abort should do nothing")
+
+					_, err = testFs.Stat(ctx, location)
+					assertions.ErrorIs(err, fs.ErrNotExist, "aborted file should not exist")
+				})
+			})
 		})
 	}
 }
@@ -0,0 +1,159 @@
+// Copyright (C) 2025 ZedCloud Org.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package filesystem
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Operation on a single file. Reports the performed action and the bytes read from src
+type fileOperation func(ctx context.Context) (kind ActionKind, transferred int64, err error)
+
+// Runs the per file operations of a sync concurrently, recording their outcome in the result
+// and reporting it to the observers.
+// Src files removed after being listed are skipped. Retryable failures are attempted again with a growing backoff.
+// Other failures are recorded, and stop the pool when failing fast.
+// Context errors always stop the pool
+type workerPool struct {
+	ctx      context.Context
+	cancel   context.CancelFunc
+	syncCtx  *SyncCtx
+	workers  chan struct{}
+	wg       sync.WaitGroup
+	failOnce sync.Once
+	failErr  error
+	mutex    sync.Mutex
+	result   *SyncResult
+}
+
+func newWorkerPool(ctx context.Context, syncCtx *SyncCtx, result *SyncResult) (pool *workerPool) {
+	ctx, cancel := context.WithCancel(ctx)
+	pool = &workerPool{
+		ctx:     ctx,
+		cancel:  cancel,
+		syncCtx: syncCtx,
+		workers: make(chan struct{}, syncCtx.Workers),
+		result:  result,
+	}
+	return pool
+}
+
+func (p *workerPool) fail(err error) {
+	p.failOnce.Do(func() {
+		p.failErr = err
+		p.cancel()
+	})
+}
+
+// Records an action performed without running an operation
+func (p *workerPool) record(kind ActionKind, location []string) {
+	p.mutex.Lock()
+	p.result.record(kind)
+	p.mutex.Unlock()
+
+	p.syncCtx.notify(Event{Kind: EventSkipped, Action: kind, Location: location})
+}
+
+// Records the outcome of the operation on the location. Returns the event reporting it
+func (p *workerPool) outcome(location []string, kind ActionKind, transferred int64, err error) (event Event) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	event = Event{Action: kind, Location: location, Bytes: transferred, Err: err}
+
+	p.result.Bytes += transferred
+	if err == nil {
+		p.result.record(kind)
+		event.Kind = EventCompleted
+		if kind == ActionSkip {
+			event.Kind = EventSkipped
+		}
+		return event
+	}
+
+	switch ClassifyError(err) {
+	case ErrorActionAbort:
+		p.fail(err)
+		event.Kind = EventFailed
+	case ErrorActionSkip:
+		p.result.Skipped++
+		event.Kind = EventSkipped
+	default:
+		p.result.Failed++
+		p.result.Errors = append(p.result.Errors, err)
+		if p.syncCtx.FailFast {
+			p.fail(err)
+		}
+		event.Kind = EventFailed
+	}
+	return event
+}
+
+// Runs the operation of the location once a worker is available.
+// Reports false when the pool was stopped
+func (p *workerPool) Go(location []string, operation fileOperation) (ok bool) {
+	select {
+	case <-p.ctx.Done():
+		return false
+	case p.workers <- struct{}{}:
+	}
+
+	p.wg.Go(func() {
+		defer func() { <-p.workers }()
+
+		kind, transferred, err := p.run(location, operation)
+		p.syncCtx.notify(p.outcome(location, kind, transferred, err))
+	})
+	return true
+}
+
+// Runs the operation, attempting it again after the failures classified as ErrorActionRetry
+func (p *workerPool) run(location []string, operation fileOperation) (kind ActionKind, transferred int64, err error) {
+	for attempt := 0; ; attempt++ {
+		var attemptTransferred int64
+		kind, attemptTransferred, err = operation(p.ctx)
+		transferred += attemptTransferred
+		if err == nil || attempt >= p.syncCtx.Retries || ClassifyError(err) != ErrorActionRetry {
+			return kind, transferred, err
+		}
+
+		p.mutex.Lock()
+		p.result.Retried++
+		p.mutex.Unlock()
+		p.syncCtx.notify(Event{Kind: EventRetry, Action: kind, Location: location, Err: err})
+
+		timer := time.NewTimer(p.syncCtx.backoff(attempt))
+		select {
+		case <-p.ctx.Done():
+			timer.Stop()
+			return kind, transferred, fmt.Errorf("stopped retrying: %w: %w", p.ctx.Err(), err)
+		case <-timer.C:
+		}
+	}
+}
+
+// Waits for the running operations. Returns the error that stopped the pool
+func (p *workerPool) Wait() (err error) {
+	p.wg.Wait()
+	return p.failErr
+}
+
+func (p *workerPool) Close() {
+	p.cancel()
+}
@@ -17,8 +17,8 @@ package directory
 
 import (
 	"context"
+	"fmt"
 	"iter"
-	"log"
 	"slices"
 	"strings"
 
@@ -28,22 +28,21 @@ import (
 // List all the domains managed by the account.
 // It uses "my_customer" as passed value for the list function.
 // Requires: https://www.googleapis.com/auth/admin.directory.domain.readonly
-func SeqDomains(ctx context.Context, svc *admin.Service) (seq iter.Seq[*admin.Domains]) {
-	return func(yield func(*admin.Domains) bool) {
+func SeqDomains(ctx context.Context, svc *admin.Service) (seq iter.Seq2[*admin.Domains, error]) {
+	return func(yield func(*admin.Domains, error) bool) {
 		domains, err := svc.Domains.
 			List("my_customer").
+			Context(ctx).
 			Do()
 		if err != nil {
-
-			log.Println("failed to retrieve domains:", err)
-			// TODO: Do something with the error
+			yield(nil, fmt.Errorf("failed to retrieve domains: %w", err))
 			return
 		}
 
 		slices.SortFunc(domains.Domains, func(a, b *admin.Domains) int { return strings.Compare(a.DomainName, b.DomainName) })
 
 		for _, domain := range domains.Domains {
-			if !yield(domain) {
+			if !yield(domain, nil) {
 				return
 			}
 		}
@@ -44,7 +44,11 @@ func Test_SeqDomains(t *testing.T) {
 			return
 		}
 
-		for domain := range directory.SeqDomains(ctx, svc) {
+		for domain, err := range directory.SeqDomains(ctx, svc) {
+			if !assertions.Nil(err, "failed to list domains") {
+				return
+			}
+
 			t.Logf("Domain: %v", domain.DomainName)
 		}
 	})
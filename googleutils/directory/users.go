@@ -17,50 +17,34 @@ package directory
 
 import (
 	"context"
+	"fmt"
 	"io"
 	"iter"
-	"log"
 
 	admin "google.golang.org/api/admin/directory/v1"
 )
 
 // Helper function for iterating over all accounts in the domain
 // This requires at least: https://www.googleapis.com/auth/admin.directory.user.readonly
-func SeqUsers(ctx context.Context, svc *admin.Service, domain string) (seq iter.Seq[*admin.User]) {
-	var doneCh = make(chan struct{}, 1)
-	var usersCh = make(chan *admin.Users, 1_000)
-
-	go func() {
-		defer close(usersCh)
-
+func SeqUsers(ctx context.Context, svc *admin.Service, domain string) (seq iter.Seq2[*admin.User, error]) {
+	return func(yield func(*admin.User, error) bool) {
+		var done bool
 		err := svc.Users.
 			List().
 			Context(ctx).
 			Domain(domain).
 			OrderBy("email").
 			Pages(ctx, func(u *admin.Users) (err error) {
-				select {
-				case usersCh <- u:
-					return nil
-				case <-doneCh:
-					return io.EOF
+				for _, user := range u.Users {
+					if !yield(user, nil) {
+						done = true
+						return io.EOF
+					}
 				}
+				return nil
 			})
-		if err != nil {
-			log.Println("failed to retrieve users:", err)
-			// TODO: What should this do with the error?
-		}
-	}()
-	return func(yield func(*admin.User) bool) {
-		defer close(doneCh)
-
-		for users := range usersCh {
-			for _, user := range users.Users {
-				if !yield(user) {
-					doneCh <- struct{}{}
-					return
-				}
-			}
+		if err != nil && !done {
+			yield(nil, fmt.Errorf("failed to retrieve users: %w", err))
 		}
 	}
 }
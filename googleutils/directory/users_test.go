@@ -44,14 +44,22 @@ func Test_SeqUsers(t *testing.T) {
 			return
 		}
 
-		for domain := range directory.SeqDomains(ctx, svc) {
+		for domain, err := range directory.SeqDomains(ctx, svc) {
+			if !assertions.Nil(err, "failed to list domains") {
+				return
+			}
+
 			t.Logf("Domain: %s", domain.DomainName)
 
 			t.Run(domain.DomainName, func(t *testing.T) {
 				assertions := assert.New(t)
 
 				var count int
-				for u := range directory.SeqUsers(ctx, svc, domain.DomainName) {
+				for u, err := range directory.SeqUsers(ctx, svc, domain.DomainName) {
+					if !assertions.Nil(err, "failed to list users") {
+						return
+					}
+
 					t.Logf("User: %v", u.PrimaryEmail)
 					count++
 				}
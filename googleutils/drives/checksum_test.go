@@ -47,13 +47,21 @@ func Test_Checksum(t *testing.T) {
 			return
 		}
 
-		for domain := range directory.SeqDomains(ctx, adminSvc) {
+		for domain, err := range directory.SeqDomains(ctx, adminSvc) {
+			if !assertions.Nil(err, "failed to list domains") {
+				return
+			}
+
 			t.Logf("Domain: %s", domain.DomainName)
 
 			t.Run(domain.DomainName, func(t *testing.T) {
 				assertions := assert.New(t)
 				var totalCount int
-				for u := range directory.SeqUsers(ctx, adminSvc, domain.DomainName) {
+				for u, err := range directory.SeqUsers(ctx, adminSvc, domain.DomainName) {
+					if !assertions.Nil(err, "failed to list users") {
+						return
+					}
+
 					t.Run(u.PrimaryEmail, func(t *testing.T) {
 						assertions := assert.New(t)
 
@@ -70,7 +78,12 @@ func Test_Checksum(t *testing.T) {
 						}
 
 						var count int
-						for location, file := range drives.SeqFiles(ctx, driveSvc) {
+						for entry, err := range drives.SeqFiles(ctx, driveSvc) {
+							if !assertions.Nil(err, "failed to list files") {
+								return
+							}
+
+							location, file := entry.Location, entry.File
 							t.Logf("[%d] File: %s - %v", count, location, file.Id)
 							t.Run(path.Join(location...), func(t *testing.T) {
 								assertions := assert.New(t)
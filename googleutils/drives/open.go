@@ -39,3 +39,19 @@ func Open(ctx context.Context, svc *drive.Service, location []string) (rc io.Rea
 	}
 	return file, nil
 }
+
+// Opens length bytes of the file starting at offset. A negative length reads until the end of the file
+func OpenRange(ctx context.Context, svc *drive.Service, location []string, offset, length int64) (rc io.ReadCloser, err error) {
+	reference, err := driveutils.FindFileByPath(ctx, location, "root", func() *drive.FilesListCall {
+		return svc.Files.List().Corpora("user")
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to find file: %w", err)
+	}
+
+	file, err := driveutils.OpenRange(ctx, svc, reference.MimeType, reference.Id, offset, length)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open file: %w", err)
+	}
+	return file, nil
+}
@@ -18,20 +18,20 @@ package driveutils
 import (
 	"context"
 	"fmt"
-	"io"
 	"iter"
-	"log"
+	"path"
 	"slices"
 	"sync"
-	"sync/atomic"
-	"time"
 
 	"google.golang.org/api/drive/v3"
 )
 
-type gdFileEntry struct {
-	path []string
-	file *drive.File
+// Maximum number of folders listed concurrently
+const MaxConcurrentListings = 16
+
+type FileEntry struct {
+	Location []string
+	File     *drive.File
 }
 
 type gdDirEntry struct {
@@ -39,111 +39,95 @@ type gdDirEntry struct {
 	asPrefix []string
 }
 
-type gdFileListEntry struct {
-	dirEntry *gdDirEntry
-	filelist *drive.FileList
+type gdResult struct {
+	entry *FileEntry
+	err   error
+}
+
+func SeqFilesFromFilesListCall(ctx context.Context, rootId string, baseCall func() *drive.FilesListCall) (seq iter.Seq2[*FileEntry, error]) {
+	return SeqFilesFromFolder(ctx, rootId, nil, true, baseCall)
 }
 
-// List all the files in the passed directory using the call as reference factory
-func SeqFilesFromFilesListCall(ctx context.Context, rootId string, baseCall func() *drive.FilesListCall) (seq iter.Seq2[[]string, *drive.File]) {
-	const MaxTimeouts = 25
-	var timeouts int
+// Lists the files contained by the folder identified by folderId.
+// Yielded locations are prefixed with prefix. When recursive is false
+// sub folders are not visited.
+// The first error found stops the listing and is the last value yielded.
+func SeqFilesFromFolder(ctx context.Context, folderId string, prefix []string, recursive bool, baseCall func() *drive.FilesListCall) (seq iter.Seq2[*FileEntry, error]) {
+	return func(yield func(*FileEntry, error) bool) {
+		ctx, cancel := context.WithCancel(ctx)
+		defer cancel()
 
-	var done atomic.Bool
-	var doneCh = make(chan struct{}, 1)
+		var resultsCh = make(chan *gdResult, 1_000)
+		var sem = make(chan struct{}, MaxConcurrentListings)
 
-	var fileListCh = make(chan *gdFileListEntry, 1_000)
-	var filesCh = make(chan *gdFileEntry, 1_000)
-	var pendingDirsCh = make(chan *gdDirEntry, 1_000)
-	pendingDirsCh <- &gdDirEntry{id: rootId}
+		send := func(result *gdResult) (ok bool) {
+			select {
+			case resultsCh <- result:
+				return true
+			case <-ctx.Done():
+				return false
+			}
+		}
 
-	go func() {
+		// Every pending folder is tracked by the WaitGroup, so the results channel
+		// is only closed once there is nothing left to list.
 		var wg sync.WaitGroup
-		defer func() {
-			wg.Wait()
-			done.Store(true)
-			close(filesCh)
-			close(pendingDirsCh)
-			close(fileListCh)
-		}()
-		for {
+		var listFolder func(dir *gdDirEntry)
+		listFolder = func(dir *gdDirEntry) {
 			select {
-			case <-doneCh:
-				return
+			case sem <- struct{}{}:
+				defer func() { <-sem }()
 			case <-ctx.Done():
 				return
-			case <-time.After(100 * time.Millisecond):
-				timeouts++
-				if timeouts >= MaxTimeouts {
-					return
-				}
-			case pendingDir := <-pendingDirsCh:
-				timeouts = 0
-				wg.Go(func() {
-					err := baseCall().
-						PageSize(1_000).
-						Q(fmt.Sprintf("trashed=false and '%s' in parents", pendingDir.id)).
-						Fields("nextPageToken,files(id,name,fullFileExtension,mimeType,modifiedTime)").
-						OrderBy("name").
-						Pages(ctx, func(fl *drive.FileList) (err error) {
-							if done.Load() {
-								return io.EOF
-							}
-
-							fileListCh <- &gdFileListEntry{
-								dirEntry: pendingDir,
-								filelist: fl,
-							}
-							return nil
-						})
-					if err != nil {
-						return
-					}
-				})
-			case entry := <-fileListCh:
-				timeouts = 0
-				wg.Go(func() {
-					defer func() {
-						if err := recover(); err != nil {
-
-							log.Println("failed to retrieve files:", err)
-							// TODO: Log on DEV builds
-						}
-					}()
-					for _, file := range entry.filelist.Files {
-						if done.Load() {
-							return
-						}
+			}
 
-						location := append(slices.Clone(entry.dirEntry.asPrefix), file.Name)
+			err := baseCall().
+				PageSize(1_000).
+				Q(fmt.Sprintf("trashed=false and '%s' in parents", dir.id)).
+				Fields("nextPageToken,files(id,name,fullFileExtension,mimeType,modifiedTime,size,md5Checksum,version)").
+				OrderBy("name").
+				Pages(ctx, func(fl *drive.FileList) (err error) {
+					for _, file := range fl.Files {
+						location := append(slices.Clone(dir.asPrefix), file.Name)
 						if file.MimeType == "application/vnd.google-apps.folder" {
-							pendingDirsCh <- &gdDirEntry{
-								id:       file.Id,
-								asPrefix: location,
+							if recursive {
+								wg.Go(func() { listFolder(&gdDirEntry{id: file.Id, asPrefix: location}) })
 							}
 							continue
 						}
 
-						filesCh <- &gdFileEntry{
-							path: location,
-							file: file,
+						if !send(&gdResult{entry: &FileEntry{Location: location, File: file}}) {
+							return ctx.Err()
 						}
 					}
+					return nil
 				})
+			if err != nil {
+				send(&gdResult{err: fmt.Errorf("failed to list folder: /%s: %w", path.Join(dir.asPrefix...), err)})
 			}
 		}
-	}()
 
-	return func(yield func([]string, *drive.File) bool) {
-		defer func() {
-			doneCh <- struct{}{}
-			close(doneCh)
+		wg.Go(func() { listFolder(&gdDirEntry{id: folderId, asPrefix: slices.Clone(prefix)}) })
+		go func() {
+			wg.Wait()
+			close(resultsCh)
 		}()
 
-		for entry := range filesCh {
-			if !yield(entry.path, entry.file) {
+		for result := range resultsCh {
+			if result.err != nil {
+				yield(nil, result.err)
+				return
+			}
+
+			if !yield(result.entry, nil) {
 				return
 			}
 		}
+
+		// Cancellation of the parent context may stop the listers before they report
+		err := ctx.Err()
+		if err != nil {
+			yield(nil, fmt.Errorf("failed to list files: %w", err))
+		}
 	}
 }
@@ -17,8 +17,8 @@ package driveutils
 
 import (
 	"context"
-	"errors"
 	"fmt"
+	"io/fs"
 
 	"google.golang.org/api/drive/v3"
 )
@@ -36,7 +36,7 @@ func FindFileByPath(ctx context.Context, location []string, startDirectory strin
 		}
 
 		if len(fl.Files) == 0 {
-			return nil, errors.New("no files found - visited files")
+			return nil, fmt.Errorf("no files found: %s: %w", part, fs.ErrNotExist)
 		}
 
 		if index == len(location)-1 {
@@ -47,5 +47,5 @@ func FindFileByPath(ctx context.Context, location []string, startDirectory strin
 	}
 
 	// If there is no reference it means the file was not found
-	return nil, errors.New("not found")
+	return nil, fmt.Errorf("empty location: %w", fs.ErrNotExist)
 }
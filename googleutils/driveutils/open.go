@@ -28,8 +28,10 @@ import (
 	"sync"
 	"unsafe"
 
+	"github.com/pluto-org-co/fsio/filesystem/utils"
 	"github.com/pluto-org-co/fsio/ioutils"
 	"google.golang.org/api/drive/v3"
+	"google.golang.org/api/googleapi"
 )
 
 type ClientExtractor struct {
@@ -128,3 +130,74 @@ func Open(ctx context.Context, svc *drive.Service, mimeType, fileId string) (rd
 	}
 	return file, nil
 }
+
+// Opens length bytes of the file starting at offset using HTTP ranges. A negative length reads until the end of the file.
+// Google Workspace documents can only be exported as a whole, for them errors.ErrUnsupported is returned
+func OpenRange(ctx context.Context, svc *drive.Service, mimeType, fileId string, offset, length int64) (rc io.ReadCloser, err error) {
+	defer func() {
+		if err != nil {
+			err = fmt.Errorf("failed to open range: %s: mimetype: %s: %w", fileId, mimeType, err)
+		}
+	}()
+
+	if mimeType == "application/vnd.google-apps.shortcut" {
+		fileInfo, err := svc.Files.
+			Get(fileId).
+			Fields("shortcutDetails").
+			SupportsAllDrives(true).
+			Context(ctx).
+			Do()
+		if err != nil {
+			return nil, fmt.Errorf("failed to retrieve file information for shortcuts: %w", err)
+		}
+		if fileInfo.ShortcutDetails != nil && fileInfo.ShortcutDetails.TargetId != "" && fileInfo.ShortcutDetails.TargetMimeType != "application/vnd.google-apps.folder" {
+			return OpenRange(ctx, svc, fileInfo.ShortcutDetails.TargetMimeType, fileInfo.ShortcutDetails.TargetId, offset, length)
+		}
+		return nil, errors.New("invalid shortcut")
+	}
+
+	if strings.HasPrefix(mimeType, "application/vnd.google-apps.") {
+		return nil, fmt.Errorf("ranged export: %w", errors.ErrUnsupported)
+	}
+
+	if length == 0 {
+		return io.NopCloser(strings.NewReader("")), nil
+	}
+
+	byteRange := fmt.Sprintf("bytes=%d-", offset)
+	if length > 0 {
+		byteRange = fmt.Sprintf("bytes=%d-%d", offset, offset+length-1)
+	}
+
+	call := svc.Files.
+		Get(fileId).
+		SupportsAllDrives(true).
+		Context(ctx)
+	call.Header().Set("Range", byteRange)
+
+	res, err := call.Download()
+	if err != nil {
+		var apiErr *googleapi.Error
+		if errors.As(err, &apiErr) && apiErr.Code == http.StatusRequestedRangeNotSatisfiable {
+			// Offset is after the end of the file
+			return io.NopCloser(strings.NewReader("")), nil
+		}
+		return nil, fmt.Errorf("failed to download file: %w", err)
+	}
+
+	if res.StatusCode == http.StatusPartialContent {
+		return res.Body, nil
+	}
+
+	// The range was ignored and the entire file is being served
+	_, err = io.CopyN(io.Discard, res.Body, offset)
+	if err != nil && !errors.Is(err, io.EOF) {
+		res.Body.Close()
+		return nil, fmt.Errorf("failed to skip contents: %w", err)
+	}
+
+	if length < 0 {
+		return res.Body, nil
+	}
+	return utils.NewSeparateReadCloser(res.Body, io.LimitReader(res.Body, length)), nil
+}
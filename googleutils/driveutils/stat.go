@@ -0,0 +1,40 @@
+// Copyright (C) 2025 ZedCloud Org.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package driveutils
+
+import (
+	"context"
+	"fmt"
+
+	"google.golang.org/api/drive/v3"
+)
+
+// Retrieves the metadata of the file by its id
+func Stat(ctx context.Context, svc *drive.Service, driveFile bool, fileId string) (file *drive.File, err error) {
+	getCall := svc.Files.
+		Get(fileId).
+		Context(ctx).
+		Fields("id,name,mimeType,md5Checksum,sha256Checksum,modifiedTime,size,version")
+	if driveFile {
+		getCall = getCall.SupportsAllDrives(true).SupportsTeamDrives(true)
+	}
+
+	file, err = getCall.Do()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get file by id: %w", err)
+	}
+	return file, nil
+}
@@ -0,0 +1,40 @@
+// Copyright (C) 2025 ZedCloud Org.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package driveutils
+
+import (
+	"context"
+	"fmt"
+
+	"google.golang.org/api/drive/v3"
+)
+
+// Moves the file by its id to the trash. Trashing a folder trashes all of its contents
+func Trash(ctx context.Context, svc *drive.Service, driveFile bool, fileId string) (err error) {
+	updateCall := svc.Files.
+		Update(fileId, &drive.File{Trashed: true}).
+		Context(ctx).
+		Fields("id")
+	if driveFile {
+		updateCall = updateCall.SupportsAllDrives(true).SupportsTeamDrives(true)
+	}
+
+	_, err = updateCall.Do()
+	if err != nil {
+		return fmt.Errorf("failed to trash file by id: %w", err)
+	}
+	return nil
+}
@@ -17,54 +17,32 @@ package gmailutils
 
 import (
 	"context"
+	"fmt"
 	"io"
 	"iter"
-	"log"
-	"sync/atomic"
 
 	"google.golang.org/api/gmail/v1"
 )
 
-type gmailMailListEntry struct {
-	MailList *gmail.ListMessagesResponse
-}
-
-func SeqMails(ctx context.Context, svc *gmail.Service) (seq iter.Seq[*gmail.Message]) {
-	var done atomic.Bool
-
-	var maillistCh = make(chan *gmailMailListEntry, 1_000)
-	go func() {
-		defer close(maillistCh)
-
+// Helper function for iterating over all the mails of the account, including spam and trash.
+// A listing failure is yielded as a non nil error with a nil message and ends the sequence
+func SeqMails(ctx context.Context, svc *gmail.Service) (seq iter.Seq2[*gmail.Message, error]) {
+	return func(yield func(*gmail.Message, error) bool) {
+		var done bool
 		err := svc.
 			Users.Messages.List("me").
 			IncludeSpamTrash(true).
 			Pages(ctx, func(lm *gmail.ListMessagesResponse) (err error) {
-				if done.Load() {
-					return io.EOF
-				}
-
-				maillistCh <- &gmailMailListEntry{
-					MailList: lm,
+				for _, msg := range lm.Messages {
+					if !yield(msg, nil) {
+						done = true
+						return io.EOF
+					}
 				}
 				return nil
 			})
-		if err != nil {
-			log.Println("failed to retrieve mails:", err)
-			return
-		}
-	}()
-
-	return func(yield func(*gmail.Message) bool) {
-		defer done.Store(true)
-
-		for entry := range maillistCh {
-			for _, msg := range entry.MailList.Messages {
-				if !yield(msg) {
-					return
-				}
-			}
+		if err != nil && !done {
+			yield(nil, fmt.Errorf("failed to retrieve mails: %w", err))
 		}
 	}
-
 }
@@ -46,14 +46,22 @@ func Test_SeqMails(t *testing.T) {
 			return
 		}
 
-		for domain := range directory.SeqDomains(ctx, svc) {
+		for domain, err := range directory.SeqDomains(ctx, svc) {
+			if !assertions.Nil(err, "failed to list domains") {
+				return
+			}
+
 			t.Logf("Domain: %s", domain.DomainName)
 
 			t.Run(domain.DomainName, func(t *testing.T) {
 				assertions := assert.New(t)
 
 				var totalCount int
-				for u := range directory.SeqUsers(ctx, svc, domain.DomainName) {
+				for u, err := range directory.SeqUsers(ctx, svc, domain.DomainName) {
+					if !assertions.Nil(err, "failed to list users") {
+						return
+					}
+
 					t.Run(u.PrimaryEmail, func(t *testing.T) {
 						assertions := assert.New(t)
 
@@ -69,7 +77,11 @@ func Test_SeqMails(t *testing.T) {
 						}
 
 						var count int
-						for mail := range gmailutils.SeqMails(ctx, gmailSvc) {
+						for mail, err := range gmailutils.SeqMails(ctx, gmailSvc) {
+							if !assertions.Nil(err, "failed to list mails") {
+								return
+							}
+
 							t.Logf("Contents: %v", mail.Id)
 							count++
 							if count >= 5 {
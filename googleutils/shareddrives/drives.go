@@ -17,8 +17,8 @@ package shareddrives
 
 import (
 	"context"
+	"fmt"
 	"iter"
-	"log"
 	"slices"
 	"strings"
 
@@ -27,10 +27,10 @@ import (
 
 // List the drives that the account can access:
 // Requires at least: https://www.googleapis.com/auth/drive
-func SeqDrives(ctx context.Context, svc *drive.Service) (seq iter.Seq[*drive.Drive]) {
+func SeqDrives(ctx context.Context, svc *drive.Service) (seq iter.Seq2[*drive.Drive, error]) {
 	const MaxPageSize = 100
 
-	return func(yield func(*drive.Drive) bool) {
+	return func(yield func(*drive.Drive, error) bool) {
 		var drives = make([]*drive.Drive, 0, MaxPageSize)
 		err := svc.Drives.
 			List().
@@ -41,15 +41,14 @@ func SeqDrives(ctx context.Context, svc *drive.Service) (seq iter.Seq[*drive.Dri
 				return nil
 			})
 		if err != nil {
-			log.Println("failed to retrieve drives:", err)
-			// TODO: What should this do with the error?
+			yield(nil, fmt.Errorf("failed to retrieve drives: %w", err))
 			return
 		}
 
 		slices.SortFunc(drives, func(a, b *drive.Drive) int { return strings.Compare(a.Name, b.Name) })
 
 		for _, drive := range drives {
-			if !yield(drive) {
+			if !yield(drive, nil) {
 				return
 			}
 		}
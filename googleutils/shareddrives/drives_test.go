@@ -46,14 +46,22 @@ func Test_SeqDrives(t *testing.T) {
 			return
 		}
 
-		for domain := range directory.SeqDomains(ctx, adminSvc) {
+		for domain, err := range directory.SeqDomains(ctx, adminSvc) {
+			if !assertions.Nil(err, "failed to list domains") {
+				return
+			}
+
 			t.Logf("Domain: %s", domain.DomainName)
 
 			t.Run(domain.DomainName, func(t *testing.T) {
 				assertions := assert.New(t)
 
 				var totalCount int
-				for u := range directory.SeqUsers(ctx, adminSvc, domain.DomainName) {
+				for u, err := range directory.SeqUsers(ctx, adminSvc, domain.DomainName) {
+					if !assertions.Nil(err, "failed to list users") {
+						return
+					}
+
 					t.Run(u.PrimaryEmail, func(t *testing.T) {
 						assertions := assert.New(t)
 
@@ -70,7 +78,11 @@ func Test_SeqDrives(t *testing.T) {
 						}
 
 						var count int
-						for drive := range shareddrives.SeqDrives(ctx, driveSvc) {
+						for drive, err := range shareddrives.SeqDrives(ctx, driveSvc) {
+							if !assertions.Nil(err, "failed to list drives") {
+								return
+							}
+
 							t.Logf("Drive: %v", drive.Name)
 							count++
 							if count >= 5 {
@@ -17,6 +17,9 @@ package shareddrives
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"io/fs"
 	"iter"
 
 	"github.com/pluto-org-co/fsio/googleutils/driveutils"
@@ -24,7 +27,7 @@ import (
 )
 
 // List the files of the passed drive
-func SeqFiles(ctx context.Context, svc *drive.Service, driveId string) (seq iter.Seq2[[]string, *drive.File]) {
+func SeqFiles(ctx context.Context, svc *drive.Service, driveId string) (seq iter.Seq2[*driveutils.FileEntry, error]) {
 	return driveutils.SeqFilesFromFilesListCall(ctx, driveId, func() (call *drive.FilesListCall) {
 		return svc.Files.
 			List().
@@ -35,3 +38,36 @@ func SeqFiles(ctx context.Context, svc *drive.Service, driveId string) (seq iter
 			DriveId(driveId)
 	})
 }
+
+// Lists the files under the folder found at location. An empty location corresponds to the drive root.
+// When location doesn't reference an existing folder the sequence is empty.
+func SeqFilesAt(ctx context.Context, svc *drive.Service, driveId string, location []string, recursive bool) (seq iter.Seq2[*driveutils.FileEntry, error]) {
+	baseCall := func() (call *drive.FilesListCall) {
+		return svc.Files.
+			List().
+			SupportsAllDrives(true).
+			IncludeItemsFromAllDrives(true).
+			IncludeTeamDriveItems(true).
+			Corpora("drive").
+			DriveId(driveId)
+	}
+
+	folderId := driveId
+	if len(location) > 0 {
+		ref, err := driveutils.FindFileByPath(ctx, location, folderId, baseCall)
+		if errors.Is(err, fs.ErrNotExist) {
+			return func(yield func(*driveutils.FileEntry, error) bool) {}
+		}
+		if err != nil {
+			return func(yield func(*driveutils.FileEntry, error) bool) {
+				yield(nil, fmt.Errorf("failed to find folder: %w", err))
+			}
+		}
+		if ref.MimeType != "application/vnd.google-apps.folder" {
+			return func(yield func(*driveutils.FileEntry, error) bool) {}
+		}
+		folderId = ref.Id
+	}
+
+	return driveutils.SeqFilesFromFolder(ctx, folderId, location, recursive, baseCall)
+}
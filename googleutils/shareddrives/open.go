@@ -45,3 +45,25 @@ func Open(ctx context.Context, svc *drive.Service, driveId string, location []st
 	}
 	return file, nil
 }
+
+// Opens length bytes of the file starting at offset. A negative length reads until the end of the file
+func OpenRange(ctx context.Context, svc *drive.Service, driveId string, location []string, offset, length int64) (rc io.ReadCloser, err error) {
+	reference, err := driveutils.FindFileByPath(ctx, location, driveId, func() *drive.FilesListCall {
+		return svc.Files.
+			List().
+			SupportsAllDrives(true).
+			IncludeItemsFromAllDrives(true).
+			IncludeTeamDriveItems(true).
+			Corpora("drive").
+			DriveId(driveId)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to find file: %w", err)
+	}
+
+	file, err := driveutils.OpenRange(ctx, svc, reference.MimeType, reference.Id, offset, length)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open file: %w", err)
+	}
+	return file, nil
+}
@@ -51,13 +51,21 @@ func Test_Open(t *testing.T) {
 			return
 		}
 
-		for domain := range directory.SeqDomains(ctx, adminSvc) {
+		for domain, err := range directory.SeqDomains(ctx, adminSvc) {
+			if !assertions.Nil(err, "failed to list domains") {
+				return
+			}
+
 			t.Logf("Domain: %s", domain.DomainName)
 
 			t.Run(domain.DomainName, func(t *testing.T) {
 				assertions := assert.New(t)
 				var totalCount int
-				for u := range directory.SeqUsers(ctx, adminSvc, domain.DomainName) {
+				for u, err := range directory.SeqUsers(ctx, adminSvc, domain.DomainName) {
+					if !assertions.Nil(err, "failed to list users") {
+						return
+					}
+
 					t.Run(u.PrimaryEmail, func(t *testing.T) {
 						assertions := assert.New(t)
 
@@ -73,14 +81,23 @@ func Test_Open(t *testing.T) {
 							return
 						}
 
-						for driveEntry := range shareddrives.SeqDrives(ctx, driveSvc) {
+						for driveEntry, err := range shareddrives.SeqDrives(ctx, driveSvc) {
+							if !assertions.Nil(err, "failed to list drives") {
+								return
+							}
+
 							t.Logf("Drive: %v", driveEntry.Name)
 							t.Run(driveEntry.Name, func(t *testing.T) {
 								ctx, cancel := context.WithTimeout(context.TODO(), time.Minute)
 								defer cancel()
 
 								var count int
-								for location, file := range shareddrives.SeqFiles(ctx, driveSvc, driveEntry.Id) {
+								for entry, err := range shareddrives.SeqFiles(ctx, driveSvc, driveEntry.Id) {
+									if !assertions.Nil(err, "failed to list files") {
+										return
+									}
+
+									location, file := entry.Location, entry.File
 									t.Logf("[%d] File: %s - %v", count, location, file.Id)
 									count++
 									if count >= 5 {
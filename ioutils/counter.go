@@ -21,8 +21,9 @@ import (
 )
 
 type CountWriter struct {
-	w     io.Writer
-	count atomic.Int64
+	w       io.Writer
+	count   atomic.Int64
+	onWrite func(count int64)
 }
 
 var _ io.Writer = (*CountWriter)(nil)
@@ -30,7 +31,10 @@ var _ io.Writer = (*CountWriter)(nil)
 func (w *CountWriter) Write(b []byte) (n int, err error) {
 	n, err = w.w.Write(b)
 	if n > 0 {
-		w.count.Add(int64(n))
+		count := w.count.Add(int64(n))
+		if w.onWrite != nil {
+			w.onWrite(count)
+		}
 	}
 	return n, err
 }
@@ -42,3 +46,8 @@ func (w *CountWriter) Count() (count int64) {
 func NewCountWriter(w io.Writer) (c *CountWriter) {
 	return &CountWriter{w: w}
 }
+
+// Like NewCountWriter, also calling onWrite with the total count after every write
+func NewCountWriterFunc(w io.Writer, onWrite func(count int64)) (c *CountWriter) {
+	return &CountWriter{w: w, onWrite: onWrite}
+}
@@ -18,6 +18,7 @@ package ioutils
 import (
 	"errors"
 	"fmt"
+	"io"
 	"net/http"
 	"sync"
 	"time"
@@ -65,3 +66,63 @@ func (r *RetryTransport) RoundTrip(req *http.Request) (res *http.Response, err e
 }
 
 var _ http.RoundTripper = (*RetryTransport)(nil)
+
+// Limits the bodies of the requests and responses going through the parent transport.
+// Unlike limiting the readers passed to a client, contents buffered by the client, like the parts of
+// multipart uploads, are also sent at the limited rate
+type RateLimitTransport struct {
+	once    sync.Once
+	Parent  http.RoundTripper
+	Limiter *RateLimiter
+}
+
+func NewRateLimitTransport(parent http.RoundTripper, limiter *RateLimiter) (rt *RateLimitTransport) {
+	return &RateLimitTransport{
+		Parent:  parent,
+		Limiter: limiter,
+	}
+}
+
+type rateLimitBody struct {
+	io.Reader
+	io.Closer
+}
+
+func (r *RateLimitTransport) limit(req *http.Request, body io.ReadCloser) (limited io.ReadCloser) {
+	return &rateLimitBody{
+		Reader: NewRateLimitReader(req.Context(), body, r.Limiter),
+		Closer: body,
+	}
+}
+
+func (r *RateLimitTransport) RoundTrip(req *http.Request) (res *http.Response, err error) {
+	r.once.Do(func() {
+		if r.Parent == nil {
+			r.Parent = http.DefaultTransport
+		}
+	})
+
+	if req.Body != nil && req.Body != http.NoBody {
+		limited := req.Clone(req.Context())
+		limited.Body = r.limit(req, req.Body)
+		if req.GetBody != nil {
+			limited.GetBody = func() (body io.ReadCloser, err error) {
+				body, err = req.GetBody()
+				if err != nil {
+					return nil, err
+				}
+				return r.limit(req, body), nil
+			}
+		}
+		req = limited
+	}
+
+	res, err = r.Parent.RoundTrip(req)
+	if err != nil {
+		return res, err
+	}
+	res.Body = r.limit(req, res.Body)
+	return res, nil
+}
+
+var _ http.RoundTripper = (*RateLimitTransport)(nil)
@@ -0,0 +1,102 @@
+// Copyright (C) 2025 ZedCloud Org.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package ioutils_test
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/pluto-org-co/fsio/ioutils"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_RateLimitTransport(t *testing.T) {
+	const (
+		rate = 128 * 1024
+		size = 3 * rate
+	)
+	// The first second of transfer is covered by the initial bucket
+	expected := time.Duration(float64(size-rate) / rate * float64(time.Second))
+
+	t.Run("Upload", func(t *testing.T) {
+		assertions := assert.New(t)
+
+		received := make(chan time.Duration, 1)
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			io.Copy(io.Discard, r.Body)
+			received <- time.Since(start)
+		}))
+		defer server.Close()
+
+		ctx, cancel := context.WithTimeout(context.TODO(), time.Minute)
+		defer cancel()
+
+		limiter := ioutils.NewRateLimiter(ioutils.ConstantRate(rate))
+		client := &http.Client{Transport: ioutils.NewRateLimitTransport(nil, limiter)}
+
+		// Buffered like the parts of multipart uploads
+		req, err := http.NewRequestWithContext(ctx, http.MethodPut, server.URL, bytes.NewReader(make([]byte, size)))
+		if !assertions.Nil(err, "failed to prepare request") {
+			return
+		}
+		res, err := client.Do(req)
+		if !assertions.Nil(err, "failed to send request") {
+			return
+		}
+		res.Body.Close()
+
+		assertions.GreaterOrEqual(<-received, expected*8/10, "server should receive the body at the limited rate")
+	})
+	t.Run("Download", func(t *testing.T) {
+		assertions := assert.New(t)
+
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write(make([]byte, size))
+		}))
+		defer server.Close()
+
+		ctx, cancel := context.WithTimeout(context.TODO(), time.Minute)
+		defer cancel()
+
+		limiter := ioutils.NewRateLimiter(ioutils.ConstantRate(rate))
+		client := &http.Client{Transport: ioutils.NewRateLimitTransport(nil, limiter)}
+
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, server.URL, nil)
+		if !assertions.Nil(err, "failed to prepare request") {
+			return
+		}
+
+		start := time.Now()
+		res, err := client.Do(req)
+		if !assertions.Nil(err, "failed to send request") {
+			return
+		}
+		defer res.Body.Close()
+
+		n, err := io.Copy(io.Discard, res.Body)
+		if !assertions.Nil(err, "failed to read response") {
+			return
+		}
+		assertions.Equal(int64(size), n, "invalid response size")
+		assertions.GreaterOrEqual(time.Since(start), expected*8/10, "response should be read at the limited rate")
+	})
+}
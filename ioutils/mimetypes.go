@@ -61,3 +61,26 @@ var OfficeLikeMimeTypes = func() (mimetypes []string) {
 	mimetypes = append(mimetypes, OpenOfficeMimeTypes...)
 	return mimetypes
 }()
+
+// Named groups of mime types
+var MimeTypeGroups = map[string][]string{
+	"google":      GoogleMimeTypes,
+	"docs-like":   DocsLikeMimeTypes,
+	"office":      OfficeMimeTypes,
+	"open-office": OpenOfficeMimeTypes,
+	"office-like": OfficeLikeMimeTypes,
+}
+
+// Replaces the group names found in MimeTypeGroups by their mime types
+func ExpandMimeTypes(names []string) (mimetypes []string) {
+	mimetypes = make([]string, 0, len(names))
+	for _, name := range names {
+		group, found := MimeTypeGroups[name]
+		if found {
+			mimetypes = append(mimetypes, group...)
+			continue
+		}
+		mimetypes = append(mimetypes, name)
+	}
+	return mimetypes
+}
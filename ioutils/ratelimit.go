@@ -0,0 +1,188 @@
+// Copyright (C) 2025 ZedCloud Org.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package ioutils
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"slices"
+	"sync"
+	"time"
+)
+
+// Bytes per second allowed at the passed time. Zero or negative is unlimited
+type RateFunc func(now time.Time) (bytesPerSecond int64)
+
+// Same rate at any time
+func ConstantRate(limit int64) (rate RateFunc) {
+	return func(now time.Time) (bytesPerSecond int64) {
+		return limit
+	}
+}
+
+// Rate applied during a daily time window
+type RateWindow struct {
+	// Time of the day the window starts and ends at, as the offset from midnight.
+	// Windows ending before they start span midnight
+	From, To time.Duration
+	// Days the window applies on. Empty applies every day
+	Days []time.Weekday
+	// Bytes per second. Zero or negative is unlimited
+	BytesPerSecond int64
+}
+
+func (w *RateWindow) contains(now time.Time) (ok bool) {
+	if len(w.Days) > 0 && !slices.Contains(w.Days, now.Weekday()) {
+		return false
+	}
+
+	midnight := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	offset := now.Sub(midnight)
+	if w.From <= w.To {
+		return offset >= w.From && offset < w.To
+	}
+	return offset >= w.From || offset < w.To
+}
+
+// Applies the rate of the first window containing the time, or the fallback rate outside every window
+func ScheduledRate(fallback int64, windows []RateWindow) (rate RateFunc) {
+	windows = slices.Clone(windows)
+	return func(now time.Time) (bytesPerSecond int64) {
+		for _, window := range windows {
+			if window.contains(now) {
+				return window.BytesPerSecond
+			}
+		}
+		return fallback
+	}
+}
+
+// Reads and writes are limited in chunks of this size, so slow rates don't wait for big buffers at once
+const rateLimitChunkSize = 32 * 1024
+
+// Token bucket shared by every reader and writer limited by it.
+// The bucket holds up to a second of transfer, consumers going over it wait for the bucket to refill
+type RateLimiter struct {
+	rate   RateFunc
+	mutex  sync.Mutex
+	tokens float64
+	last   time.Time
+}
+
+func NewRateLimiter(rate RateFunc) (l *RateLimiter) {
+	return &RateLimiter{rate: rate}
+}
+
+// Takes n bytes from the bucket, waiting until the bucket can cover them
+func (l *RateLimiter) WaitN(ctx context.Context, n int) (err error) {
+	wait := l.reserve(n)
+	if wait <= 0 {
+		return nil
+	}
+
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return fmt.Errorf("context error during rate limit: %w", ctx.Err())
+	case <-timer.C:
+		return nil
+	}
+}
+
+// Takes the bytes from the bucket, reporting how long the consumer must wait for the bucket to cover them
+func (l *RateLimiter) reserve(n int) (wait time.Duration) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	now := time.Now()
+	rate := float64(l.rate(now))
+	if rate <= 0 {
+		l.tokens = 0
+		l.last = now
+		return 0
+	}
+
+	if l.last.IsZero() {
+		l.tokens = rate
+	} else {
+		l.tokens = min(rate, l.tokens+now.Sub(l.last).Seconds()*rate)
+	}
+	l.last = now
+
+	l.tokens -= float64(n)
+	if l.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-l.tokens / rate * float64(time.Second))
+}
+
+type RateLimitReader struct {
+	ctx     context.Context
+	r       io.Reader
+	limiter *RateLimiter
+}
+
+var _ io.Reader = (*RateLimitReader)(nil)
+
+func (r *RateLimitReader) Read(b []byte) (n int, err error) {
+	n, err = r.r.Read(b[:min(len(b), rateLimitChunkSize)])
+	if n > 0 {
+		waitErr := r.limiter.WaitN(r.ctx, n)
+		if waitErr != nil && err == nil {
+			err = waitErr
+		}
+	}
+	return n, err
+}
+
+// Limits the reads of r with the limiter. Waits stop when the context is done
+func NewRateLimitReader(ctx context.Context, r io.Reader, limiter *RateLimiter) (l *RateLimitReader) {
+	return &RateLimitReader{ctx: ctx, r: r, limiter: limiter}
+}
+
+type RateLimitWriter struct {
+	ctx     context.Context
+	w       io.Writer
+	limiter *RateLimiter
+}
+
+var _ io.Writer = (*RateLimitWriter)(nil)
+
+func (w *RateLimitWriter) Write(b []byte) (n int, err error) {
+	for len(b) > 0 {
+		chunk := b[:min(len(b), rateLimitChunkSize)]
+		err = w.limiter.WaitN(w.ctx, len(chunk))
+		if err != nil {
+			return n, err
+		}
+
+		written, err := w.w.Write(chunk)
+		n += written
+		if err != nil {
+			return n, err
+		}
+		b = b[written:]
+	}
+	return n, nil
+}
+
+// Limits the writes into w with the limiter. Waits stop when the context is done
+func NewRateLimitWriter(ctx context.Context, w io.Writer, limiter *RateLimiter) (l *RateLimitWriter) {
+	return &RateLimitWriter{ctx: ctx, w: w, limiter: limiter}
+}